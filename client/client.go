@@ -1,11 +1,18 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	neturl "net/url"
+	"strings"
 	"time"
 
 	"github.com/kylape/host-manager/internal/state"
@@ -15,20 +22,96 @@ import (
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
+
+	// Token is sent as a bearer token on every request when set
+	Token string
 }
 
-// NewClient creates a new host manager client
+// NewClient creates a new host manager client. baseURL may be an http(s)
+// URL or unix:///path/to/socket to talk to a local Unix domain socket.
 func NewClient(baseURL string) *Client {
 	if baseURL == "" {
 		baseURL = "http://host.docker.internal:8080"
 	}
 
-	return &Client{
+	var base http.RoundTripper = http.DefaultTransport
+	if socketPath, ok := strings.CutPrefix(baseURL, "unix://"); ok {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+		base = transport
+		baseURL = "http://unix"
+	}
+
+	c := &Client{
 		BaseURL: baseURL,
-		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
 	}
+	c.HTTPClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &authTransport{client: c, base: base},
+	}
+	return c
+}
+
+// ConfigureTLS sets up HTTPS for the client. When caFile is set, only server
+// certificates signed by that CA are trusted. When certFile and keyFile are
+// set, the client presents that certificate for mutual TLS.
+func (c *Client) ConfigureTLS(caFile, certFile, keyFile string) error {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		data, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	t, ok := c.HTTPClient.Transport.(*authTransport)
+	if !ok {
+		t = &authTransport{client: c, base: c.HTTPClient.Transport}
+		c.HTTPClient.Transport = t
+	}
+
+	// Keep any existing dialer, such as the Unix socket one
+	base, ok := t.base.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.TLSClientConfig = tlsConfig
+	t.base = transport
+	return nil
+}
+
+// authTransport adds the client's bearer token to outgoing requests
+type authTransport struct {
+	client *Client
+	base   http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.client.Token == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.client.Token)
+	return t.base.RoundTrip(req)
 }
 
 // Health checks the service health
@@ -72,9 +155,37 @@ func (c *Client) GetHostStatus() (*state.HostState, error) {
 	return &hostState, nil
 }
 
-// ListClusters returns all clusters
-func (c *Client) ListClusters() ([]state.ClusterResponse, error) {
-	resp, err := c.HTTPClient.Get(c.BaseURL + "/clusters")
+// KubernetesVersions returns the Kubernetes versions the server can create
+// clusters with
+func (c *Client) KubernetesVersions() (*state.KubernetesVersionsResponse, error) {
+	resp, err := c.HTTPClient.Get(c.BaseURL + "/kubernetes-versions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kubernetes versions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get Kubernetes versions failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var versions state.KubernetesVersionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("failed to decode Kubernetes versions: %w", err)
+	}
+
+	return &versions, nil
+}
+
+// ListClusters returns all clusters, or those matching a label selector
+// unless it is empty
+func (c *Client) ListClusters(selector string) ([]state.ClusterResponse, error) {
+	url := c.BaseURL + "/clusters"
+	if selector != "" {
+		url += "?selector=" + neturl.QueryEscape(selector)
+	}
+
+	resp, err := c.HTTPClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list clusters: %w", err)
 	}
@@ -96,13 +207,8 @@ func (c *Client) ListClusters() ([]state.ClusterResponse, error) {
 	return response.Clusters, nil
 }
 
-// CreateCluster creates a new cluster
-func (c *Client) CreateCluster(name string, kubevirt bool) (*state.ClusterResponse, error) {
-	req := state.ClusterCreateRequest{
-		Name:     name,
-		KubeVirt: kubevirt,
-	}
-
+// CreateCluster starts creating a new cluster and returns the tracking operation
+func (c *Client) CreateCluster(req state.ClusterCreateRequest) (*state.Operation, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -114,21 +220,12 @@ func (c *Client) CreateCluster(name string, kubevirt bool) (*state.ClusterRespon
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
+	if resp.StatusCode != http.StatusAccepted {
 		body, _ := ioutil.ReadAll(resp.Body)
 		return nil, fmt.Errorf("create cluster failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var response struct {
-		Success bool                   `json:"success"`
-		Cluster state.ClusterResponse `json:"cluster"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode create response: %w", err)
-	}
-
-	return &response.Cluster, nil
+	return decodeOperation(resp, "create")
 }
 
 // GetCluster returns details for a specific cluster
@@ -156,31 +253,267 @@ func (c *Client) GetCluster(name string) (*state.ClusterResponse, error) {
 	return &cluster, nil
 }
 
-// DeleteCluster deletes a cluster
-func (c *Client) DeleteCluster(name string) error {
-	req, err := http.NewRequest("DELETE", c.BaseURL+"/clusters/"+name, nil)
+// UpdateCluster changes properties of a cluster and returns it as updated
+func (c *Client) UpdateCluster(name string, update state.ClusterUpdateRequest) (*state.ClusterResponse, error) {
+	body, err := json.Marshal(update)
 	if err != nil {
-		return fmt.Errorf("failed to create delete request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	req, err := http.NewRequest("PATCH", c.BaseURL+"/clusters/"+name, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create update request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to delete cluster: %w", err)
+		return nil, fmt.Errorf("failed to update cluster: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("cluster %s not found", name)
+		return nil, fmt.Errorf("cluster %s not found", name)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("delete cluster failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("update cluster failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
+	var cluster state.ClusterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&cluster); err != nil {
+		return nil, fmt.Errorf("failed to decode cluster response: %w", err)
+	}
+
+	return &cluster, nil
+}
+
+// DeleteCluster starts deleting a cluster and returns the tracking
+// operation. dataPolicy overrides the cluster's data policy unless empty.
+func (c *Client) DeleteCluster(name, dataPolicy string) (*state.Operation, error) {
+	url := c.BaseURL + "/clusters/" + name
+	if dataPolicy != "" {
+		url += "?data=" + dataPolicy
+	}
+
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delete request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete cluster: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("delete cluster failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return decodeOperation(resp, "delete")
+}
+
+// StopCluster starts stopping a cluster's node containers and returns the
+// tracking operation
+func (c *Client) StopCluster(name string) (*state.Operation, error) {
+	return c.clusterAction(name, "stop")
+}
+
+// StartCluster starts a stopped cluster and returns the tracking operation
+func (c *Client) StartCluster(name string) (*state.Operation, error) {
+	return c.clusterAction(name, "start")
+}
+
+// RestartCluster starts restarting a cluster and returns the tracking
+// operation
+func (c *Client) RestartCluster(name string) (*state.Operation, error) {
+	return c.clusterAction(name, "restart")
+}
+
+// clusterAction posts to a cluster's action endpoint and returns the
+// operation it started
+func (c *Client) clusterAction(name, action string) (*state.Operation, error) {
+	resp, err := c.HTTPClient.Post(c.BaseURL+"/clusters/"+name+"/"+action, "application/json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s cluster: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s cluster failed with status %d: %s", action, resp.StatusCode, string(body))
+	}
+
+	return decodeOperation(resp, action)
+}
+
+// AdoptCluster takes over management of an existing kind cluster and
+// returns it as recorded by the server
+func (c *Client) AdoptCluster(name string) (*state.ClusterResponse, error) {
+	resp, err := c.HTTPClient.Post(c.BaseURL+"/clusters/"+name+"/adopt", "application/json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt cluster: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("cluster %s not found in kind", name)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("adopt cluster failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Cluster state.ClusterResponse `json:"cluster"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode adopt response: %w", err)
+	}
+
+	return &result.Cluster, nil
+}
+
+// StreamEvents follows the Server-Sent Events stream of a cluster, calling
+// handler for each event until handler returns false or the stream ends
+func (c *Client) StreamEvents(cluster string, handler func(state.ClusterEvent) bool) error {
+	// The stream outlives the regular request timeout
+	streamClient := *c.HTTPClient
+	streamClient.Timeout = 0
+
+	resp, err := streamClient.Get(c.BaseURL + "/clusters/" + cluster + "/events")
+	if err != nil {
+		return fmt.Errorf("failed to stream events: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("stream events failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			var event state.ClusterEvent
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return fmt.Errorf("failed to decode event: %w", err)
+			}
+			data.Reset()
+			if !handler(event) {
+				return nil
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
 	return nil
 }
 
+// ListOperations returns all operations tracked by the server
+func (c *Client) ListOperations() ([]state.Operation, error) {
+	resp, err := c.HTTPClient.Get(c.BaseURL + "/operations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list operations failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Operations []state.Operation `json:"operations"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode operations response: %w", err)
+	}
+
+	return response.Operations, nil
+}
+
+// GetOperation returns the current state of an operation
+func (c *Client) GetOperation(id string) (*state.Operation, error) {
+	resp, err := c.HTTPClient.Get(c.BaseURL + "/operations/" + id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("operation %s not found", id)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get operation failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var op state.Operation
+	if err := json.NewDecoder(resp.Body).Decode(&op); err != nil {
+		return nil, fmt.Errorf("failed to decode operation response: %w", err)
+	}
+
+	return &op, nil
+}
+
+// WaitOperation polls an operation every interval until it completes. An
+// operation that finishes in the failed phase is returned along with an error.
+func (c *Client) WaitOperation(id string, interval time.Duration) (*state.Operation, error) {
+	for {
+		op, err := c.GetOperation(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Phase {
+		case state.OperationSucceeded:
+			return op, nil
+		case state.OperationFailed:
+			return op, fmt.Errorf("operation %s failed: %s", op.ID, op.Error)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// decodeOperation decodes the operation from an accepted mutation response
+func decodeOperation(resp *http.Response, action string) (*state.Operation, error) {
+	var response struct {
+		Success   bool            `json:"success"`
+		Operation state.Operation `json:"operation"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response: %w", action, err)
+	}
+
+	return &response.Operation, nil
+}
+
 // GetKubeconfig returns the kubeconfig for a cluster
 func (c *Client) GetKubeconfig(name string) (string, error) {
 	resp, err := c.HTTPClient.Get(c.BaseURL + "/clusters/" + name + "/kubeconfig")
@@ -0,0 +1,77 @@
+// bundle-manifests downloads the upstream manifests that are compiled into
+// host-manager, the KubeVirt operator and the built-in addons, so that
+// clusters can be created without network access to the projects
+// publishing them. Run it from the repository root whenever a
+// bundled version changes, review the diff and commit the files it writes.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kylape/host-manager/internal/addons"
+	"github.com/kylape/host-manager/internal/kubevirt"
+)
+
+// timeout bounds each download
+const timeout = 2 * time.Minute
+
+func main() {
+	root := flag.String("root", ".", "Repository root")
+	flag.Parse()
+
+	client := &http.Client{Timeout: timeout}
+	dir := filepath.Join(*root, "internal", "kubevirt", kubevirt.BundleDir)
+	if err := fetch(client, kubevirt.ReleaseURL+kubevirt.OperatorManifest, filepath.Join(dir, kubevirt.OperatorManifest)); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, name := range addons.Builtin() {
+		addon, err := addons.Lookup(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		dir := filepath.Join(*root, "internal", "addons", addons.BundleDir, name)
+		for n, url := range addon.URLs {
+			if err := fetch(client, url, filepath.Join(dir, addons.BundleFile(n, url))); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+}
+
+// fetch downloads url to path and prints its SHA-256 digest for review
+func fetch(client *http.Client, url, path string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download of %s failed with status %d", url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	fmt.Printf("%s  %s\n", hex.EncodeToString(sum[:]), path)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultServerURL = "http://host.docker.internal:8080"
+
+	// tokenEnvVar holds the API token when --token is not given
+	tokenEnvVar = "HM_TOKEN"
+)
+
+// config holds hm-client settings read from the config file
+type config struct {
+	Server     string `json:"server,omitempty"`
+	Token      string `json:"token,omitempty"`
+	CACert     string `json:"ca_cert,omitempty"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+}
+
+// defaultConfigPath returns ~/.config/hm-client/config.json
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hm-client", "config.json")
+}
+
+// loadConfig reads the config file at path. A missing file yields an empty config.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// resolveToken picks the API token from the flag, the environment or the
+// config file, in that order
+func resolveToken(flagToken string, cfg *config) string {
+	if flagToken != "" {
+		return flagToken
+	}
+	if token := os.Getenv(tokenEnvVar); token != "" {
+		return token
+	}
+	return cfg.Token
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -4,16 +4,30 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kylape/host-manager/client"
+	"github.com/kylape/host-manager/internal/state"
 )
 
+// pollInterval is how often operation status is polled while waiting
+const pollInterval = 2 * time.Second
+
 func main() {
 	var (
-		serverURL = flag.String("server", "http://host.docker.internal:8080", "Host manager server URL")
-		help      = flag.Bool("help", false, "Show help message")
+		serverURL  = flag.String("server", "", "Host manager server URL (default "+defaultServerURL+")")
+		token      = flag.String("token", "", "API bearer token (default $"+tokenEnvVar+" or the config file)")
+		configPath = flag.String("config", defaultConfigPath(), "Path to the hm-client config file")
+		caCert     = flag.String("ca-cert", "", "CA certificate to trust for HTTPS (pins the server CA)")
+		clientCert = flag.String("client-cert", "", "Client certificate for mutual TLS")
+		clientKey  = flag.String("client-key", "", "Client private key for mutual TLS")
+		help       = flag.Bool("help", false, "Show help message")
 	)
 
 	flag.Parse()
@@ -23,7 +37,24 @@ func main() {
 		return
 	}
 
-	hmc := client.NewClient(*serverURL)
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	baseURL := firstNonEmpty(*serverURL, cfg.Server, defaultServerURL)
+
+	hmc := client.NewClient(baseURL)
+	hmc.Token = resolveToken(*token, cfg)
+
+	ca := firstNonEmpty(*caCert, cfg.CACert)
+	cert := firstNonEmpty(*clientCert, cfg.ClientCert)
+	key := firstNonEmpty(*clientKey, cfg.ClientKey)
+	if ca != "" || cert != "" || key != "" {
+		if err := hmc.ConfigureTLS(ca, cert, key); err != nil {
+			log.Fatalf("Failed to configure TLS: %v", err)
+		}
+	}
 	command := flag.Args()[0]
 
 	switch command {
@@ -35,6 +66,10 @@ func main() {
 		handleClusters(hmc, flag.Args()[1:])
 	case "registry":
 		handleRegistry(hmc, flag.Args()[1:])
+	case "operations":
+		handleOperations(hmc, flag.Args()[1:])
+	case "versions":
+		handleVersions(hmc)
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		showHelp()
@@ -64,9 +99,14 @@ func handleHostStatus(hmc *client.Client) {
 }
 
 func handleClusters(hmc *client.Client, args []string) {
-	if len(args) == 0 {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
 		// List clusters
-		clusters, err := hmc.ListClusters()
+		fs := flag.NewFlagSet("clusters", flag.ExitOnError)
+		selector := fs.String("l", "", "Only list clusters matching this label selector, e.g. team=storage,env!=ci")
+		fs.StringVar(selector, "selector", "", "Same as -l")
+		fs.Parse(args)
+
+		clusters, err := hmc.ListClusters(*selector)
 		if err != nil {
 			log.Fatalf("Failed to list clusters: %v", err)
 		}
@@ -76,10 +116,18 @@ func handleClusters(hmc *client.Client, args []string) {
 			return
 		}
 
-		fmt.Printf("%-20s %-10s %-15s %-8s\n", "NAME", "STATUS", "TYPE", "KUBEVIRT")
-		fmt.Printf("%-20s %-10s %-15s %-8s\n", "----", "------", "----", "--------")
+		fmt.Printf("%-20s %-10s %-15s %-8s %-10s %-12s\n", "NAME", "STATUS", "TYPE", "KUBEVIRT", "HEALTH", "OWNER")
+		fmt.Printf("%-20s %-10s %-15s %-8s %-10s %-12s\n", "----", "------", "----", "--------", "------", "-----")
 		for _, cluster := range clusters {
-			fmt.Printf("%-20s %-10s %-15s %-8v\n", cluster.Name, cluster.Status, cluster.Type, cluster.KubeVirt)
+			health := "-"
+			if cluster.Health != nil {
+				health = cluster.Health.Status
+			}
+			owner := "-"
+			if cluster.Owner != "" {
+				owner = cluster.Owner
+			}
+			fmt.Printf("%-20s %-10s %-15s %-8v %-10s %-12s\n", cluster.Name, cluster.Status, cluster.Type, cluster.KubeVirt, health, owner)
 		}
 		return
 	}
@@ -88,32 +136,234 @@ func handleClusters(hmc *client.Client, args []string) {
 	switch subcommand {
 	case "create":
 		if len(args) < 2 {
-			fmt.Println("Usage: clusters create <name> [--kubevirt]")
+			fmt.Println("Usage: clusters create <name> [--kubernetes-version V | --node-image IMAGE] [--control-planes N] [--workers N] [--topology FILE] [--node-ports LIST] [--mount NAME:PATH]... [--retain-data] [--addon NAME[:K=V,...]]... [--ttl DURATION | --expires-at TIME] [--owner NAME] [--label KEY=VALUE]... [--annotation KEY=VALUE]... [--kubevirt] [--keep-on-failure] [--async | --follow]")
 			os.Exit(1)
 		}
 		name := args[1]
-		kubevirt := len(args) > 2 && args[2] == "--kubevirt"
 
-		cluster, err := hmc.CreateCluster(name, kubevirt)
+		fs := flag.NewFlagSet("clusters create", flag.ExitOnError)
+		kubevirt := fs.Bool("kubevirt", false, "Enable KubeVirt in the cluster")
+		keepOnFailure := fs.Bool("keep-on-failure", false, "Keep a failed cluster and its logs instead of rolling back")
+		version := fs.String("kubernetes-version", "", "Kubernetes version, e.g. 1.31 or v1.31.4 (see the versions command)")
+		nodeImage := fs.String("node-image", "", "Explicit kind node image")
+		controlPlanes := fs.Int("control-planes", 0, "Number of control-plane nodes: 1, 3 or 5")
+		workers := fs.Int("workers", 0, "Number of worker nodes")
+		topologyFile := fs.String("topology", "", "JSON file with the full topology, including per-node labels and taints")
+		nodePorts := fs.String("node-ports", "", "Comma-separated NodePort service ports to expose on host ports")
+		var mounts mountFlag
+		fs.Var(&mounts, "mount", "Extra data directory NAME mounted at PATH in every node (repeatable)")
+		retainData := fs.Bool("retain-data", false, "Keep the cluster's data directory when it is deleted")
+		var addonList addonFlag
+		fs.Var(&addonList, "addon", "Addon to install, with optional parameters: NAME[:KEY=VALUE,...] (repeatable)")
+		ttl := fs.String("ttl", "", "Delete the cluster automatically after this duration, e.g. 8h")
+		expires := fs.String("expires-at", "", "Delete the cluster automatically at this RFC 3339 time")
+		owner := fs.String("owner", "", "Owner of the cluster (default: the authenticated user)")
+		labels := metadataFlag{}
+		fs.Var(labels, "label", "Label KEY=VALUE (repeatable)")
+		annotations := metadataFlag{}
+		fs.Var(annotations, "annotation", "Annotation KEY=VALUE (repeatable)")
+		async := fs.Bool("async", false, "Return immediately instead of waiting for completion")
+		follow := fs.Bool("follow", false, "Stream kind output while the cluster is created")
+		fs.Parse(args[2:])
+
+		topology, err := loadTopology(*topologyFile, *controlPlanes, *workers)
+		if err != nil {
+			log.Fatalf("Invalid topology: %v", err)
+		}
+
+		ports, err := parsePorts(*nodePorts)
+		if err != nil {
+			log.Fatalf("Invalid --node-ports: %v", err)
+		}
+
+		expiresAt, err := parseTime(*expires)
+		if err != nil {
+			log.Fatalf("Invalid --expires-at: %v", err)
+		}
+
+		labelValues, err := labels.values()
+		if err != nil {
+			log.Fatalf("Invalid --label: %v", err)
+		}
+		annotationValues, err := annotations.values()
+		if err != nil {
+			log.Fatalf("Invalid --annotation: %v", err)
+		}
+
+		op, err := hmc.CreateCluster(state.ClusterCreateRequest{
+			Name:              name,
+			KubeVirt:          *kubevirt,
+			KeepOnFailure:     *keepOnFailure,
+			KubernetesVersion: *version,
+			NodeImage:         *nodeImage,
+			Topology:          topology,
+			NodePorts:         ports,
+			Mounts:            mounts,
+			DataPolicy:        dataPolicyFor(*retainData),
+			Addons:            addonList,
+			TTL:               *ttl,
+			ExpiresAt:         expiresAt,
+			Owner:             *owner,
+			Labels:            labelValues,
+			Annotations:       annotationValues,
+		})
 		if err != nil {
 			log.Fatalf("Failed to create cluster: %v", err)
 		}
 
-		fmt.Printf("Cluster %s created successfully\n", cluster.Name)
+		if *async {
+			fmt.Printf("Cluster %s creation started (operation %s)\n", name, op.ID)
+			return
+		}
+
+		if *follow {
+			followOperation(hmc, name, op.ID)
+			fmt.Printf("Cluster %s created successfully\n", name)
+			return
+		}
+
+		fmt.Printf("Creating cluster %s (operation %s)...\n", name, op.ID)
+		waitForOperation(hmc, op.ID)
+		fmt.Printf("Cluster %s created successfully\n", name)
 
 	case "delete":
 		if len(args) < 2 {
-			fmt.Println("Usage: clusters delete <name>")
+			fmt.Println("Usage: clusters delete <name> [--keep-data | --delete-data] [--async]")
 			os.Exit(1)
 		}
 		name := args[1]
 
-		if err := hmc.DeleteCluster(name); err != nil {
+		fs := flag.NewFlagSet("clusters delete", flag.ExitOnError)
+		keepData := fs.Bool("keep-data", false, "Retain the cluster's data directory regardless of its data policy")
+		deleteData := fs.Bool("delete-data", false, "Remove the cluster's data directory regardless of its data policy")
+		async := fs.Bool("async", false, "Return immediately instead of waiting for completion")
+		fs.Parse(args[2:])
+
+		dataPolicy := ""
+		if *keepData {
+			dataPolicy = state.DataRetain
+		} else if *deleteData {
+			dataPolicy = state.DataDelete
+		}
+
+		op, err := hmc.DeleteCluster(name, dataPolicy)
+		if err != nil {
 			log.Fatalf("Failed to delete cluster: %v", err)
 		}
 
+		if *async {
+			fmt.Printf("Cluster %s deletion started (operation %s)\n", name, op.ID)
+			return
+		}
+
+		fmt.Printf("Deleting cluster %s (operation %s)...\n", name, op.ID)
+		waitForOperation(hmc, op.ID)
 		fmt.Printf("Cluster %s deleted successfully\n", name)
 
+	case "update":
+		if len(args) < 2 {
+			fmt.Println("Usage: clusters update <name> [--ttl DURATION | --expires-at TIME | --no-expiry] [--owner NAME] [--label KEY=VALUE | --label KEY-]... [--annotation KEY=VALUE | --annotation KEY-]...")
+			os.Exit(1)
+		}
+		name := args[1]
+
+		fs := flag.NewFlagSet("clusters update", flag.ExitOnError)
+		ttl := fs.String("ttl", "", "Expire the cluster this long from now, e.g. 8h")
+		expires := fs.String("expires-at", "", "Expire the cluster at this RFC 3339 time")
+		noExpiry := fs.Bool("no-expiry", false, "Never expire the cluster")
+		owner := fs.String("owner", "", "New owner of the cluster")
+		labels := metadataFlag{}
+		fs.Var(labels, "label", "Set label KEY=VALUE, or remove it with KEY- (repeatable)")
+		annotations := metadataFlag{}
+		fs.Var(annotations, "annotation", "Set annotation KEY=VALUE, or remove it with KEY- (repeatable)")
+		fs.Parse(args[2:])
+
+		update := state.ClusterUpdateRequest{
+			TTL:         *ttl,
+			NoExpiry:    *noExpiry,
+			Labels:      labels,
+			Annotations: annotations,
+		}
+		// An explicit empty --owner clears the owner
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "owner" {
+				update.Owner = owner
+			}
+		})
+
+		expiresAt, err := parseTime(*expires)
+		if err != nil {
+			log.Fatalf("Invalid --expires-at: %v", err)
+		}
+		update.ExpiresAt = expiresAt
+
+		cluster, err := hmc.UpdateCluster(name, update)
+		if err != nil {
+			log.Fatalf("Failed to update cluster: %v", err)
+		}
+
+		if len(cluster.Labels) > 0 {
+			fmt.Printf("Cluster %s labels: %s\n", name, formatLabels(cluster.Labels))
+		}
+		if cluster.ExpiresAt != nil {
+			fmt.Printf("Cluster %s expires at %s\n", name, cluster.ExpiresAt.Local().Format(time.RFC3339))
+		} else {
+			fmt.Printf("Cluster %s does not expire\n", name)
+		}
+
+	case "stop", "start", "restart":
+		if len(args) < 2 {
+			fmt.Printf("Usage: clusters %s <name> [--async]\n", subcommand)
+			os.Exit(1)
+		}
+		name := args[1]
+
+		fs := flag.NewFlagSet("clusters "+subcommand, flag.ExitOnError)
+		async := fs.Bool("async", false, "Return immediately instead of waiting for completion")
+		fs.Parse(args[2:])
+
+		actions := map[string]struct {
+			run        func(string) (*state.Operation, error)
+			verb, done string
+		}{
+			"stop":    {hmc.StopCluster, "Stopping", "stopped"},
+			"start":   {hmc.StartCluster, "Starting", "started"},
+			"restart": {hmc.RestartCluster, "Restarting", "restarted"},
+		}
+		action := actions[subcommand]
+
+		op, err := action.run(name)
+		if err != nil {
+			log.Fatalf("Failed to %s cluster: %v", subcommand, err)
+		}
+
+		if *async {
+			fmt.Printf("%s cluster %s (operation %s)\n", action.verb, name, op.ID)
+			return
+		}
+
+		fmt.Printf("%s cluster %s (operation %s)...\n", action.verb, name, op.ID)
+		waitForOperation(hmc, op.ID)
+		fmt.Printf("Cluster %s %s successfully\n", name, action.done)
+
+	case "adopt":
+		if len(args) < 2 {
+			fmt.Println("Usage: clusters adopt <name>")
+			os.Exit(1)
+		}
+		name := args[1]
+
+		cluster, err := hmc.AdoptCluster(name)
+		if err != nil {
+			log.Fatalf("Failed to adopt cluster: %v", err)
+		}
+
+		fmt.Printf("Cluster %s adopted\n", name)
+		fmt.Printf("Kubernetes: %s\n", cluster.KubernetesVersion)
+		fmt.Printf("Node image: %s\n", cluster.NodeImage)
+		fmt.Printf("Nodes: %s\n", strings.Join(cluster.Nodes, ", "))
+		fmt.Printf("Registry: %v\n", cluster.Registry)
+
 	case "get":
 		if len(args) < 2 {
 			fmt.Println("Usage: clusters get <name>")
@@ -150,6 +400,192 @@ func handleClusters(hmc *client.Client, args []string) {
 	}
 }
 
+// loadTopology builds the requested topology from a JSON file and/or node
+// counts given on the command line, which take precedence
+func loadTopology(path string, controlPlanes, workers int) (*state.Topology, error) {
+	if path == "" && controlPlanes == 0 && workers == 0 {
+		return nil, nil
+	}
+
+	topology := &state.Topology{}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, topology); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	if controlPlanes != 0 {
+		topology.ControlPlanes = controlPlanes
+	}
+	if workers != 0 {
+		topology.Workers = workers
+	}
+	return topology, nil
+}
+
+// mountFlag collects repeated --mount NAME:PATH flags
+type mountFlag []state.Mount
+
+func (m *mountFlag) String() string {
+	var mounts []string
+	for _, mount := range *m {
+		mounts = append(mounts, mount.Name+":"+mount.ContainerPath)
+	}
+	return strings.Join(mounts, ",")
+}
+
+func (m *mountFlag) Set(value string) error {
+	name, path, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("expected NAME:PATH, got %q", value)
+	}
+	*m = append(*m, state.Mount{Name: name, ContainerPath: path})
+	return nil
+}
+
+// addonFlag collects repeated --addon NAME[:KEY=VALUE,...] flags
+type addonFlag []state.AddonRequest
+
+func (a *addonFlag) String() string {
+	var names []string
+	for _, addon := range *a {
+		names = append(names, addon.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (a *addonFlag) Set(value string) error {
+	name, params, _ := strings.Cut(value, ":")
+	addon := state.AddonRequest{Name: name}
+	for _, param := range strings.Split(params, ",") {
+		if param == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(param, "=")
+		if !ok {
+			return fmt.Errorf("expected KEY=VALUE, got %q", param)
+		}
+		if addon.Params == nil {
+			addon.Params = map[string]string{}
+		}
+		addon.Params[key] = val
+	}
+	*a = append(*a, addon)
+	return nil
+}
+
+// metadataFlag collects repeated --label and --annotation KEY=VALUE flags.
+// KEY- removes the key when updating a cluster.
+type metadataFlag map[string]*string
+
+func (m metadataFlag) String() string {
+	return formatLabels(m.set())
+}
+
+func (m metadataFlag) Set(value string) error {
+	if key := strings.TrimSuffix(value, "-"); key != value && !strings.Contains(value, "=") {
+		m[key] = nil
+		return nil
+	}
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected KEY=VALUE or KEY-, got %q", value)
+	}
+	m[key] = &val
+	return nil
+}
+
+// set returns the keys given a value
+func (m metadataFlag) set() map[string]string {
+	values := make(map[string]string)
+	for key, value := range m {
+		if value != nil {
+			values[key] = *value
+		}
+	}
+	return values
+}
+
+// values returns the flags for a create request, which cannot remove keys
+func (m metadataFlag) values() (map[string]string, error) {
+	for key, value := range m {
+		if value == nil {
+			return nil, fmt.Errorf("%s- removes a key and only applies to updates", key)
+		}
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m.set(), nil
+}
+
+// formatLabels renders labels as sorted KEY=VALUE pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// dataPolicyFor returns the data policy requested by --retain-data
+func dataPolicyFor(retain bool) string {
+	if retain {
+		return state.DataRetain
+	}
+	return ""
+}
+
+// parseTime parses an optional RFC 3339 time
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("use RFC 3339, e.g. 2025-01-31T18:00:00Z")
+	}
+	return &t, nil
+}
+
+// parsePorts parses a comma-separated list of ports
+func parsePorts(list string) ([]int, error) {
+	var ports []int
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		port, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+func handleVersions(hmc *client.Client) {
+	versions, err := hmc.KubernetesVersions()
+	if err != nil {
+		log.Fatalf("Failed to get Kubernetes versions: %v", err)
+	}
+
+	fmt.Printf("%-10s %-8s %s\n", "VERSION", "DEFAULT", "IMAGE")
+	for _, v := range versions.Versions {
+		isDefault := ""
+		if v.Version == versions.Default {
+			isDefault = "*"
+		}
+		fmt.Printf("%-10s %-8s %s\n", v.Version, isDefault, v.Image)
+	}
+}
+
 func handleRegistry(hmc *client.Client, args []string) {
 	if len(args) == 0 {
 		// Show registry status
@@ -179,6 +615,105 @@ func handleRegistry(hmc *client.Client, args []string) {
 	}
 }
 
+func handleOperations(hmc *client.Client, args []string) {
+	if len(args) == 0 {
+		// List operations
+		ops, err := hmc.ListOperations()
+		if err != nil {
+			log.Fatalf("Failed to list operations: %v", err)
+		}
+
+		if len(ops) == 0 {
+			fmt.Println("No operations found")
+			return
+		}
+
+		fmt.Printf("%-18s %-16s %-20s %-10s %-20s\n", "ID", "TYPE", "CLUSTER", "PHASE", "STARTED")
+		fmt.Printf("%-18s %-16s %-20s %-10s %-20s\n", "--", "----", "-------", "-----", "-------")
+		for _, op := range ops {
+			fmt.Printf("%-18s %-16s %-20s %-10s %-20s\n", op.ID, op.Type, op.Cluster, op.Phase, op.StartTime.Format(time.RFC3339))
+		}
+		return
+	}
+
+	subcommand := args[0]
+	switch subcommand {
+	case "get":
+		if len(args) < 2 {
+			fmt.Println("Usage: operations get <id>")
+			os.Exit(1)
+		}
+
+		op, err := hmc.GetOperation(args[1])
+		if err != nil {
+			log.Fatalf("Failed to get operation: %v", err)
+		}
+
+		data, _ := json.MarshalIndent(op, "", "  ")
+		fmt.Println(string(data))
+
+	case "wait":
+		if len(args) < 2 {
+			fmt.Println("Usage: operations wait <id>")
+			os.Exit(1)
+		}
+
+		op := waitForOperation(hmc, args[1])
+		fmt.Printf("Operation %s %s\n", op.ID, op.Phase)
+
+	default:
+		fmt.Printf("Unknown operations subcommand: %s\n", subcommand)
+		showHelp()
+		os.Exit(1)
+	}
+}
+
+// followOperation streams a cluster's events, printing output lines of the
+// given operation until it completes, and exits if it failed
+func followOperation(hmc *client.Client, cluster, id string) {
+	var completed *state.ClusterEvent
+	err := hmc.StreamEvents(cluster, func(event state.ClusterEvent) bool {
+		if event.Operation != id {
+			return true
+		}
+
+		switch event.Type {
+		case state.EventOutput:
+			fmt.Println(event.Message)
+		case state.EventCompleted:
+			completed = &event
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		log.Fatalf("Failed to follow operation: %v", err)
+	}
+
+	if completed == nil {
+		// The stream ended early; fall back to polling
+		waitForOperation(hmc, id)
+		return
+	}
+
+	if completed.Phase == state.OperationFailed {
+		log.Fatalf("Operation failed: operation %s failed: %s", id, completed.Error)
+	}
+}
+
+// waitForOperation polls an operation until it completes, printing its
+// captured output and exiting if it failed
+func waitForOperation(hmc *client.Client, id string) *state.Operation {
+	op, err := hmc.WaitOperation(id, pollInterval)
+	if err != nil {
+		if op != nil && op.Output != "" {
+			fmt.Fprint(os.Stderr, op.Output)
+		}
+		log.Fatalf("Operation failed: %v", err)
+	}
+	return op
+}
+
 func showHelp() {
 	fmt.Printf(`Host Manager Client - CLI tool for managing the host manager service
 
@@ -186,18 +721,55 @@ Usage: %s [options] <command> [args...]
 
 Options:
   --server URL    Host manager server URL (default: http://host.docker.internal:8080)
+  --token TOKEN   API bearer token (default: $HM_TOKEN, then the config file)
+  --config PATH   Config file (default: ~/.config/hm-client/config.json)
+  --ca-cert FILE  Trust only this CA for HTTPS servers
+  --client-cert FILE  Client certificate for mutual TLS
+  --client-key FILE   Client private key for mutual TLS
   --help          Show this help message
 
+Config file:
+  {"server": "https://host.docker.internal:8080", "token": "hm_...",
+   "ca_cert": "/path/ca.crt", "client_cert": "/path/me.crt", "client_key": "/path/me.key"}
+
 Commands:
   health                          Check service health
   status                          Show detailed host status
-  clusters                        List all clusters
-  clusters create <name> [--kubevirt]  Create new cluster
-  clusters delete <name>          Delete cluster
+  clusters [-l SELECTOR]          List all clusters, or those whose labels match
+                                  SELECTOR (KEY=VALUE, KEY!=VALUE, KEY, !KEY, ...)
+  clusters create <name> [--kubernetes-version V | --node-image IMAGE]
+                  [--control-planes N] [--workers N] [--topology FILE]
+                  [--node-ports LIST] [--mount NAME:PATH]... [--retain-data]
+                  [--addon NAME[:KEY=VALUE,...]]...
+                  [--ttl DURATION | --expires-at TIME]
+                  [--owner NAME] [--label KEY=VALUE]... [--annotation KEY=VALUE]...
+                  [--kubevirt] [--keep-on-failure] [--async | --follow]
+                                  Create new cluster; failed creates are rolled
+                                  back unless --keep-on-failure is given;
+                                  with --ttl or --expires-at it is deleted
+                                  automatically when it expires
+  clusters delete <name> [--keep-data | --delete-data] [--async]
+                                  Delete cluster; its data directory is removed
+                                  or retained per its data policy
+  clusters update <name> [--ttl DURATION | --expires-at TIME | --no-expiry]
+                  [--owner NAME] [--label KEY=VALUE | --label KEY-]...
+                  [--annotation KEY=VALUE | --annotation KEY-]...
+                                  Extend or remove a cluster's expiry and change
+                                  its owner, labels and annotations
+  clusters stop <name> [--async]  Stop a cluster's node containers, keeping its
+                                  ports, data and nodes for a later start
+  clusters start <name> [--async] Start a stopped cluster and wait for its API server
+  clusters restart <name> [--async]
+                                  Stop and start a cluster
+  clusters adopt <name>           Manage a kind cluster created outside host-manager
   clusters get <name>             Get cluster details
   clusters kubeconfig <name>      Get cluster kubeconfig
   registry                        Show registry status
   registry start                  Start registry
+  operations                      List cluster operations
+  operations get <id>             Show operation details and output
+  operations wait <id>            Wait for an operation to complete
+  versions                        List supported Kubernetes versions
 
 Examples:
   # Check if service is healthy
@@ -212,13 +784,55 @@ Examples:
   # Create cluster with KubeVirt
   %s clusters create vm-cluster --kubevirt
 
+  # Create a cluster running the newest supported 1.30 release
+  %s clusters create old-k8s --kubernetes-version 1.30
+
+  # Create an HA cluster with three control planes and two workers
+  %s clusters create ha --control-planes 3 --workers 2
+
+  # Label and taint individual nodes; topology.json contains e.g.
+  # {"workers": 2, "nodes": [{"role": "worker", "index": 1,
+  #   "labels": {"gpu": "true"}, "taints": [{"key": "gpu", "effect": "NoSchedule"}]}]}
+  %s clusters create tainted --topology topology.json
+
+  # Expose NodePort services 30080 and 30443 on allocated host ports
+  %s clusters create web --node-ports 30080,30443
+
+  # Mount a dedicated data directory at /data and keep it after delete
+  %s clusters create db --mount pgdata:/data --retain-data
+
+  # Install addons; local-path stores volumes under the given node path
+  %s clusters create apps --addon ingress-nginx --addon cert-manager --addon local-path:path=/local/volumes
+
+  # Create a throwaway cluster deleted after 8 hours, then extend it
+  %s clusters create scratch --ttl 8h
+  %s clusters update scratch --ttl 24h
+
+  # Label clusters by team and list one team's clusters outside CI
+  %s clusters create storage-dev --label team=storage --annotation ticket=STOR-123
+  %s clusters -l team=storage,env!=ci
+
   # Get kubeconfig for a cluster
   %s clusters kubeconfig my-dev-cluster > ~/.kube/config
 
   # Delete a cluster
   %s clusters delete my-dev-cluster
 
+  # Create a cluster and stream kind's output while it is built
+  %s clusters create my-dev-cluster --follow
+
+  # Start creating a cluster without waiting, then wait for it later
+  %s clusters create my-dev-cluster --async
+  %s operations wait <operation-id>
+
+  # Free the host's CPU and memory while a cluster is idle
+  %s clusters stop my-dev-cluster
+  %s clusters start my-dev-cluster
+
+  # Take over a cluster created by hand with kind
+  %s clusters adopt legacy-cluster
+
   # Check registry status
   %s registry
-`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 }
\ No newline at end of file
@@ -0,0 +1,229 @@
+package addons
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// Dir holds one directory of manifests per addon. The *.yaml files in an
+// addon's directory are applied in name order; built-in addons whose
+// directory is empty use the manifests bundled in the binary. A directory
+// with an addon.json defines an addon of its own or overrides a built-in one.
+const Dir = "/var/lib/host-manager/addons"
+
+// BundleDir is the directory of this package holding the manifests of the
+// built-in addons, one subdirectory per addon, written by
+// cmd/bundle-manifests
+const BundleDir = "bundle"
+
+//go:embed bundle
+var bundled embed.FS
+
+// bundle and hostDir are where manifests are looked up; tests replace them
+var (
+	bundle  fs.FS = bundled
+	hostDir       = Dir
+)
+
+// BundleFile names the bundled copy of the nth upstream manifest of an
+// addon, prefixed with its position so files apply in the listed order
+func BundleFile(n int, url string) string {
+	return fmt.Sprintf("%02d-%s", n, path.Base(url))
+}
+
+// definitionFile describes an on-host addon
+const definitionFile = "addon.json"
+
+// definition is the content of an addon.json
+type definition struct {
+	Version  string   `json:"version,omitempty"`
+	Requires []string `json:"requires,omitempty"`
+	Waits    []Wait   `json:"waits,omitempty"`
+}
+
+// Install is an addon with the parameters it is installed with
+type Install struct {
+	*Addon
+	Params map[string]string
+}
+
+// Lookup returns the addon called name, defined on the host or built in
+func Lookup(name string) (*Addon, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid addon name %q", name)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(hostDir, name, definitionFile))
+	if os.IsNotExist(err) {
+		if addon, ok := builtin[name]; ok {
+			return addon, nil
+		}
+		return nil, fmt.Errorf("unknown addon %q (built-in: %s; or add %s)", name, strings.Join(Builtin(), ", "), filepath.Join(hostDir, name, definitionFile))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read addon %s: %w", name, err)
+	}
+
+	var def definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("invalid %s for addon %s: %w", definitionFile, name, err)
+	}
+	addon := &Addon{Name: name, Version: def.Version, Requires: def.Requires, Waits: def.Waits}
+	if base, ok := builtin[name]; ok {
+		// An override keeps the built-in parameters and kind adjustments
+		addon.Params = base.Params
+		addon.NodeLabels = base.NodeLabels
+		addon.Ports = base.Ports
+		addon.validate = base.validate
+		addon.render = base.render
+	}
+	return addon, nil
+}
+
+// Builtin returns the names of the built-in addons
+func Builtin() []string {
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve validates the addons of a create request and returns them in
+// install order: each after the addons it requires, which are added with
+// their default parameters when not requested.
+func Resolve(requests []state.AddonRequest) ([]Install, error) {
+	params := make(map[string]map[string]string)
+	for _, req := range requests {
+		if _, dup := params[req.Name]; dup {
+			return nil, fmt.Errorf("addon %s is listed twice", req.Name)
+		}
+		if req.Params == nil {
+			req.Params = map[string]string{}
+		}
+		params[req.Name] = req.Params
+	}
+
+	var ordered []Install
+	visiting := make(map[string]bool)
+	done := make(map[string]bool)
+
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		if done[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("addon dependency cycle: %s", strings.Join(append(chain, name), " -> "))
+		}
+		visiting[name] = true
+
+		addon, err := Lookup(name)
+		if err != nil {
+			return err
+		}
+		for _, dep := range addon.Requires {
+			if err := visit(dep, append(chain, name)); err != nil {
+				return err
+			}
+		}
+
+		install, err := withParams(addon, params[name])
+		if err != nil {
+			return err
+		}
+		ordered = append(ordered, install)
+		done[name] = true
+		return nil
+	}
+
+	for _, req := range requests {
+		if err := visit(req.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// withParams checks requested parameters against those the addon accepts
+// and fills in defaults
+func withParams(addon *Addon, requested map[string]string) (Install, error) {
+	params := make(map[string]string)
+	for key, value := range addon.Params {
+		params[key] = value
+	}
+	for key, value := range requested {
+		if _, ok := addon.Params[key]; !ok {
+			return Install{}, fmt.Errorf("addon %s has no parameter %q", addon.Name, key)
+		}
+		params[key] = value
+	}
+	if addon.validate != nil {
+		if err := addon.validate(params); err != nil {
+			return Install{}, fmt.Errorf("addon %s: %w", addon.Name, err)
+		}
+	}
+	if len(params) == 0 {
+		params = nil
+	}
+	return Install{Addon: addon, Params: params}, nil
+}
+
+// Manifest returns the manifests of an install as one YAML stream, read from
+// the addon's directory on the host or else, for a built-in addon, from the
+// bundle
+func (i Install) Manifest() ([]byte, error) {
+	dir := filepath.Join(hostDir, i.Name)
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var contents [][]byte
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		contents = append(contents, data)
+	}
+
+	// Overrides of a built-in addon have no URLs and must bring manifests
+	if len(contents) == 0 && len(i.URLs) > 0 {
+		for n, url := range i.URLs {
+			data, err := fs.ReadFile(bundle, path.Join(BundleDir, i.Name, BundleFile(n, url)))
+			if err != nil {
+				return nil, fmt.Errorf("addon %s has no manifests in %s and is not bundled in this binary (build it after running cmd/bundle-manifests): %w", i.Name, dir, err)
+			}
+			contents = append(contents, data)
+		}
+	}
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("addon %s has no manifests in %s", i.Name, dir)
+	}
+
+	var b strings.Builder
+	for _, data := range contents {
+		b.WriteString("---\n")
+		b.Write(data)
+		b.WriteString("\n")
+	}
+
+	manifest := b.String()
+	if i.render != nil {
+		manifest = i.render(manifest, i.Params)
+	}
+	return []byte(manifest), nil
+}
@@ -0,0 +1,170 @@
+package addons
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// withBundle makes the built-in addons load from a bundle holding a stub of
+// each upstream manifest, with no manifests on the host
+func withBundle(t *testing.T) {
+	stubs := fstest.MapFS{}
+	for name, addon := range builtin {
+		for n, url := range addon.URLs {
+			stub := "kind: Stub\naddon: " + name + "\nargs:\n- --metric-resolution=15s\npath: \"/opt/local-path-provisioner\"\n"
+			stubs[path.Join(BundleDir, name, BundleFile(n, url))] = &fstest.MapFile{Data: []byte(stub)}
+		}
+	}
+
+	oldBundle, oldDir := bundle, hostDir
+	t.Cleanup(func() { bundle, hostDir = oldBundle, oldDir })
+	bundle, hostDir = stubs, t.TempDir()
+}
+
+// withBuiltin adds addons to the built-in catalog for one test
+func withBuiltin(t *testing.T, addons ...*Addon) {
+	for _, addon := range addons {
+		builtin[addon.Name] = addon
+	}
+	t.Cleanup(func() {
+		for _, addon := range addons {
+			delete(builtin, addon.Name)
+		}
+	})
+}
+
+func names(installs []Install) string {
+	var n []string
+	for _, install := range installs {
+		n = append(n, install.Name)
+	}
+	return strings.Join(n, ",")
+}
+
+func TestBuiltinAddonsLoad(t *testing.T) {
+	withBundle(t)
+
+	for _, name := range Builtin() {
+		installs, err := Resolve([]state.AddonRequest{{Name: name}})
+		if err != nil {
+			t.Errorf("Resolve(%s) = %v", name, err)
+			continue
+		}
+		manifest, err := installs[len(installs)-1].Manifest()
+		if err != nil {
+			t.Errorf("Manifest(%s) = %v", name, err)
+			continue
+		}
+		if !strings.Contains(string(manifest), "addon: "+name) {
+			t.Errorf("manifest of %s does not hold its bundled file:\n%s", name, manifest)
+		}
+	}
+}
+
+func TestBuiltinRender(t *testing.T) {
+	withBundle(t)
+
+	tests := []struct {
+		request state.AddonRequest
+		want    string
+	}{
+		{state.AddonRequest{Name: "local-path"}, `path: "` + localPathDefault + `"`},
+		{state.AddonRequest{Name: "local-path", Params: map[string]string{"path": "/local/volumes/"}}, `path: "/local/volumes"`},
+		{state.AddonRequest{Name: "metrics-server"}, "--kubelet-insecure-tls"},
+	}
+	for _, test := range tests {
+		installs, err := Resolve([]state.AddonRequest{test.request})
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := installs[0].Manifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(manifest), test.want) {
+			t.Errorf("%+v: manifest lacks %q:\n%s", test.request, test.want, manifest)
+		}
+	}
+}
+
+func TestResolveOrder(t *testing.T) {
+	withBundle(t)
+	withBuiltin(t,
+		&Addon{Name: "app", URLs: []string{"https://example.com/app.yaml"}, Requires: []string{"gateway", "local-path"}},
+		&Addon{Name: "gateway", URLs: []string{"https://example.com/gateway.yaml"}, Requires: []string{"cert-manager"}},
+	)
+
+	tests := []struct {
+		requests []state.AddonRequest
+		want     string
+	}{
+		{[]state.AddonRequest{{Name: "app"}}, "cert-manager,gateway,local-path,app"},
+		{[]state.AddonRequest{{Name: "metrics-server"}, {Name: "app"}}, "metrics-server,cert-manager,gateway,local-path,app"},
+		{[]state.AddonRequest{{Name: "cert-manager"}, {Name: "gateway"}}, "cert-manager,gateway"},
+	}
+	for _, test := range tests {
+		installs, err := Resolve(test.requests)
+		if err != nil {
+			t.Errorf("Resolve(%+v) = %v", test.requests, err)
+			continue
+		}
+		if got := names(installs); got != test.want {
+			t.Errorf("Resolve(%+v) order %s, want %s", test.requests, got, test.want)
+		}
+	}
+
+	// Requested parameters apply to an addon also pulled in as a dependency
+	installs, err := Resolve([]state.AddonRequest{{Name: "app"}, {Name: "local-path", Params: map[string]string{"path": "/local/x"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, install := range installs {
+		if install.Name == "local-path" && install.Params["path"] != "/local/x" {
+			t.Errorf("local-path params %v, want the requested path", install.Params)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	withBundle(t)
+	withBuiltin(t,
+		&Addon{Name: "loop-a", Requires: []string{"loop-b"}},
+		&Addon{Name: "loop-b", Requires: []string{"loop-a"}},
+	)
+
+	tests := []struct {
+		requests []state.AddonRequest
+		want     string
+	}{
+		{[]state.AddonRequest{{Name: "loop-a"}}, "cycle"},
+		{[]state.AddonRequest{{Name: "nope"}}, "unknown addon"},
+		{[]state.AddonRequest{{Name: "../x"}}, "invalid addon name"},
+		{[]state.AddonRequest{{Name: "cert-manager"}, {Name: "cert-manager"}}, "listed twice"},
+		{[]state.AddonRequest{{Name: "cert-manager", Params: map[string]string{"x": "y"}}}, "no parameter"},
+		{[]state.AddonRequest{{Name: "local-path", Params: map[string]string{"path": "relative"}}}, "must be absolute"},
+	}
+	for _, test := range tests {
+		_, err := Resolve(test.requests)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Resolve(%+v) = %v, want an error containing %q", test.requests, err, test.want)
+		}
+	}
+}
+
+func TestManifestNotBundled(t *testing.T) {
+	oldBundle, oldDir := bundle, hostDir
+	t.Cleanup(func() { bundle, hostDir = oldBundle, oldDir })
+	bundle, hostDir = fstest.MapFS{}, t.TempDir()
+
+	installs, err := Resolve([]state.AddonRequest{{Name: "cert-manager"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := installs[0].Manifest(); err == nil || !strings.Contains(err.Error(), "bundle-manifests") {
+		t.Errorf("Manifest() = %v, want an error pointing at cmd/bundle-manifests", err)
+	}
+}
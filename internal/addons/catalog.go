@@ -0,0 +1,95 @@
+package addons
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Wait is a readiness condition checked with kubectl wait after an addon's
+// manifests are applied
+type Wait struct {
+	Namespace string `json:"namespace,omitempty"`
+	Resource  string `json:"resource"`  // e.g. "deployment/metrics-server"
+	Condition string `json:"condition"` // e.g. "Available"
+}
+
+// Addon describes an installable addon
+type Addon struct {
+	Name     string
+	Version  string
+	URLs     []string          // upstream manifests, bundled by cmd/bundle-manifests
+	Requires []string          // addons that must be installed first
+	Waits    []Wait            // readiness conditions, checked in order
+	Params   map[string]string // accepted parameters and their defaults
+
+	// NodeLabels are set on the first control-plane node and Ports are
+	// forwarded to it from allocated host ports
+	NodeLabels map[string]string
+	Ports      []int
+
+	// validate checks parameters when the addon is requested; render
+	// adjusts the manifests for kind and applies the parameters
+	validate func(params map[string]string) error
+	render   func(manifest string, params map[string]string) string
+}
+
+// localPathDefault keeps volumes in the cluster's data directory, which is
+// mounted at /local on every node
+const localPathDefault = "/local/local-path-provisioner"
+
+// builtin are the addons known without an on-host definition
+var builtin = map[string]*Addon{
+	"cert-manager": {
+		Name:    "cert-manager",
+		Version: "v1.16.2",
+		URLs:    []string{"https://github.com/cert-manager/cert-manager/releases/download/v1.16.2/cert-manager.yaml"},
+		Waits: []Wait{
+			{Namespace: "cert-manager", Resource: "deployment/cert-manager", Condition: "Available"},
+			{Namespace: "cert-manager", Resource: "deployment/cert-manager-cainjector", Condition: "Available"},
+			{Namespace: "cert-manager", Resource: "deployment/cert-manager-webhook", Condition: "Available"},
+		},
+	},
+	"ingress-nginx": {
+		Name:    "ingress-nginx",
+		Version: "v1.12.0",
+		URLs:    []string{"https://raw.githubusercontent.com/kubernetes/ingress-nginx/controller-v1.12.0/deploy/static/provider/kind/deploy.yaml"},
+		Waits: []Wait{
+			{Namespace: "ingress-nginx", Resource: "deployment/ingress-nginx-controller", Condition: "Available"},
+		},
+		// The kind provider runs the controller with host ports 80 and 443
+		// on the node labeled ingress-ready
+		NodeLabels: map[string]string{"ingress-ready": "true"},
+		Ports:      []int{80, 443},
+	},
+	"local-path": {
+		Name:    "local-path",
+		Version: "v0.0.30",
+		URLs:    []string{"https://raw.githubusercontent.com/rancher/local-path-provisioner/v0.0.30/deploy/local-path-storage.yaml"},
+		Waits: []Wait{
+			{Namespace: "local-path-storage", Resource: "deployment/local-path-provisioner", Condition: "Available"},
+		},
+		Params: map[string]string{"path": localPathDefault},
+		validate: func(params map[string]string) error {
+			if !path.IsAbs(params["path"]) {
+				return fmt.Errorf("path must be absolute, got %q", params["path"])
+			}
+			return nil
+		},
+		render: func(manifest string, params map[string]string) string {
+			return strings.ReplaceAll(manifest, `"/opt/local-path-provisioner"`, fmt.Sprintf("%q", path.Clean(params["path"])))
+		},
+	},
+	"metrics-server": {
+		Name:    "metrics-server",
+		Version: "v0.7.2",
+		URLs:    []string{"https://github.com/kubernetes-sigs/metrics-server/releases/download/v0.7.2/components.yaml"},
+		Waits: []Wait{
+			{Namespace: "kube-system", Resource: "deployment/metrics-server", Condition: "Available"},
+		},
+		// kind kubelets serve self-signed certificates
+		render: func(manifest string, params map[string]string) string {
+			return strings.Replace(manifest, "- --metric-resolution=15s", "- --metric-resolution=15s\n        - --kubelet-insecure-tls", 1)
+		},
+	},
+}
@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the caller's identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the caller's identity, if the request was authenticated
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
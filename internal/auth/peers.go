@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// DefaultPeerPolicy grants root full access over the Unix socket
+const DefaultPeerPolicy = "uid:0=admin"
+
+// Peer is the process on the other end of a Unix socket connection
+type Peer struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// PeerPolicy maps Unix socket peers to roles by UID or group membership
+type PeerPolicy struct {
+	uids map[uint32]string
+	gids map[uint32]string
+}
+
+// ParsePeerPolicy parses a comma-separated list of rules such as
+// "uid:0=admin,gid:1000=developer,user:alice=developer,group:wheel=admin"
+func ParsePeerPolicy(spec string) (*PeerPolicy, error) {
+	p := &PeerPolicy{
+		uids: make(map[uint32]string),
+		gids: make(map[uint32]string),
+	}
+
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		subject, role, ok := strings.Cut(rule, "=")
+		if !ok || !ValidRole(role) {
+			return nil, fmt.Errorf("invalid peer rule %q: expected <subject>=<viewer|developer|admin>", rule)
+		}
+
+		kind, value, ok := strings.Cut(subject, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid peer rule %q: subject must be uid:, gid:, user: or group:", rule)
+		}
+
+		switch kind {
+		case "uid", "gid":
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid peer rule %q: %w", rule, err)
+			}
+			if kind == "uid" {
+				p.uids[uint32(id)] = role
+			} else {
+				p.gids[uint32(id)] = role
+			}
+		case "user":
+			u, err := user.Lookup(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid peer rule %q: %w", rule, err)
+			}
+			id, _ := strconv.ParseUint(u.Uid, 10, 32)
+			p.uids[uint32(id)] = role
+		case "group":
+			g, err := user.LookupGroup(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid peer rule %q: %w", rule, err)
+			}
+			id, _ := strconv.ParseUint(g.Gid, 10, 32)
+			p.gids[uint32(id)] = role
+		default:
+			return nil, fmt.Errorf("invalid peer rule %q: unknown subject type %s", rule, kind)
+		}
+	}
+
+	return p, nil
+}
+
+// Identity returns the identity of a peer, granting the most privileged role
+// matched by its UID, primary GID or supplementary groups
+func (p *PeerPolicy) Identity(peer Peer) (*Identity, bool) {
+	role := p.uids[peer.UID]
+
+	gids := []uint32{peer.GID}
+	if u, err := user.LookupId(strconv.FormatUint(uint64(peer.UID), 10)); err == nil {
+		if groups, err := u.GroupIds(); err == nil {
+			for _, g := range groups {
+				if id, err := strconv.ParseUint(g, 10, 32); err == nil {
+					gids = append(gids, uint32(id))
+				}
+			}
+		}
+	}
+	for _, gid := range gids {
+		if r, ok := p.gids[gid]; ok && roleRank[r] > roleRank[role] {
+			role = r
+		}
+	}
+
+	if role == "" {
+		return nil, false
+	}
+
+	name := fmt.Sprintf("uid:%d", peer.UID)
+	if u, err := user.LookupId(strconv.FormatUint(uint64(peer.UID), 10)); err == nil {
+		name = u.Username
+	}
+	return &Identity{Name: name, Role: role}, true
+}
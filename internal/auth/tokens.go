@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const TokenFilePath = "/etc/host-manager-tokens.json"
+
+// Roles, in increasing order of privilege
+const (
+	RoleViewer    = "viewer"    // read-only access
+	RoleDeveloper = "developer" // create, delete and use development clusters
+	RoleAdmin     = "admin"     // host-level operations
+)
+
+var roleRank = map[string]int{
+	RoleViewer:    1,
+	RoleDeveloper: 2,
+	RoleAdmin:     3,
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAllows reports whether a caller with role have may perform an action
+// requiring role need
+func RoleAllows(have, need string) bool {
+	return roleRank[have] >= roleRank[need] && roleRank[need] > 0
+}
+
+// Identity is an authenticated API caller
+type Identity struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// TokenEntry is a single API token as stored on disk. Only the SHA-256 hash
+// of the token is kept.
+type TokenEntry struct {
+	Name    string     `json:"name"`
+	Role    string     `json:"role"`
+	Hash    string     `json:"hash"`
+	Created *time.Time `json:"created,omitempty"`
+}
+
+type tokenFile struct {
+	Tokens []TokenEntry `json:"tokens"`
+}
+
+// TokenStore is a file-backed store of hashed API tokens. The file is
+// re-read when it changes on disk so tokens can be added while the server is
+// running.
+type TokenStore struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	tokens  []TokenEntry
+}
+
+// NewTokenStore creates a token store backed by the file at path
+func NewTokenStore(path string) (*TokenStore, error) {
+	s := &TokenStore{path: path}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Authenticate returns the identity owning token
+func (s *TokenStore) Authenticate(token string) (*Identity, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reloadIfChangedLocked() // Ignore errors - keep serving from the last good copy
+
+	hash := hashToken(token)
+	var found *Identity
+	for _, entry := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(entry.Hash), []byte(hash)) == 1 {
+			found = &Identity{Name: entry.Name, Role: entry.Role}
+		}
+	}
+	return found, found != nil
+}
+
+// Add generates a new token for name with the given role, persists its hash
+// and returns the plaintext token. An existing token for name is replaced.
+func (s *TokenStore) Add(name, role string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("token name is required")
+	}
+	if !ValidRole(role) {
+		return "", fmt.Errorf("unknown role %q", role)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := "hm_" + hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadIfChangedLocked(); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	entries := []TokenEntry{}
+	for _, entry := range s.tokens {
+		if entry.Name != name {
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, TokenEntry{
+		Name:    name,
+		Role:    role,
+		Hash:    hashToken(token),
+		Created: &now,
+	})
+
+	if err := s.saveLocked(entries); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Remove deletes the token belonging to name
+func (s *TokenStore) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadIfChangedLocked(); err != nil {
+		return err
+	}
+
+	entries := []TokenEntry{}
+	found := false
+	for _, entry := range s.tokens {
+		if entry.Name == name {
+			found = true
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if !found {
+		return fmt.Errorf("no token named %s", name)
+	}
+
+	return s.saveLocked(entries)
+}
+
+// reload reads the token file from disk
+func (s *TokenStore) reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloadIfChangedLocked()
+}
+
+// reloadIfChangedLocked re-reads the token file if its modification time
+// changed. The caller must hold s.mu.
+func (s *TokenStore) reloadIfChangedLocked() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.tokens = nil
+			s.modTime = time.Time{}
+			return nil
+		}
+		return fmt.Errorf("failed to stat token file: %w", err)
+	}
+
+	if info.ModTime().Equal(s.modTime) && s.tokens != nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	var file tokenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse token file: %w", err)
+	}
+
+	for _, entry := range file.Tokens {
+		if !ValidRole(entry.Role) {
+			return fmt.Errorf("token %s has unknown role %q", entry.Name, entry.Role)
+		}
+	}
+
+	s.tokens = file.Tokens
+	if s.tokens == nil {
+		s.tokens = []TokenEntry{}
+	}
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// saveLocked writes entries to the token file. The caller must hold s.mu.
+func (s *TokenStore) saveLocked(entries []TokenEntry) error {
+	data, err := json.MarshalIndent(tokenFile{Tokens: entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+
+	if err := ioutil.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+
+	s.tokens = entries
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// hashToken returns the stored representation of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
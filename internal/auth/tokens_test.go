@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		have, need string
+		want       bool
+	}{
+		{RoleViewer, RoleViewer, true},
+		{RoleViewer, RoleDeveloper, false},
+		{RoleViewer, RoleAdmin, false},
+		{RoleDeveloper, RoleViewer, true},
+		{RoleDeveloper, RoleDeveloper, true},
+		{RoleDeveloper, RoleAdmin, false},
+		{RoleAdmin, RoleViewer, true},
+		{RoleAdmin, RoleAdmin, true},
+		{"", RoleViewer, false},
+		{"root", RoleViewer, false},
+		{RoleAdmin, "", false},
+		{RoleAdmin, "superuser", false},
+	}
+	for _, tt := range tests {
+		if got := RoleAllows(tt.have, tt.need); got != tt.want {
+			t.Errorf("RoleAllows(%q, %q) = %v, want %v", tt.have, tt.need, got, tt.want)
+		}
+	}
+}
+
+func TestTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	store, err := NewTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice, err := store.Add("alice", RoleDeveloper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := store.Add("bob", RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), alice) || !strings.Contains(string(data), hashToken(alice)) {
+		t.Errorf("token file should hold only the hash of each token:\n%s", data)
+	}
+
+	// A second store reads the file written by the first
+	reread, err := NewTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		token string
+		want  *Identity
+	}{
+		{alice, &Identity{Name: "alice", Role: RoleDeveloper}},
+		{bob, &Identity{Name: "bob", Role: RoleAdmin}},
+		{alice + "x", nil},
+		{hashToken(alice), nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, ok := reread.Authenticate(tt.token)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("Authenticate(%q) = %+v, want rejection", tt.token, got)
+			}
+			continue
+		}
+		if !ok || *got != *tt.want {
+			t.Errorf("Authenticate(%q) = %+v, %v, want %+v", tt.token, got, ok, tt.want)
+		}
+	}
+
+	// Adding a token for an existing name replaces the old one
+	replaced, err := store.Add("alice", RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Authenticate(alice); ok {
+		t.Error("replaced token still authenticates")
+	}
+	if got, ok := store.Authenticate(replaced); !ok || got.Role != RoleViewer {
+		t.Errorf("Authenticate(replacement) = %+v, %v", got, ok)
+	}
+
+	if err := store.Remove("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Authenticate(bob); ok {
+		t.Error("removed token still authenticates")
+	}
+	if err := store.Remove("bob"); err == nil {
+		t.Error("removing an unknown token succeeded")
+	}
+	if _, err := store.Add("carol", "root"); err == nil {
+		t.Error("adding a token with an unknown role succeeded")
+	}
+	if _, err := store.Add("", RoleViewer); err == nil {
+		t.Error("adding a token without a name succeeded")
+	}
+}
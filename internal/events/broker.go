@@ -0,0 +1,161 @@
+package events
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kylape/host-manager/internal/state"
+)
+
+const (
+	// maxHistory is the number of events kept per cluster for late subscribers
+	maxHistory = 1000
+
+	// subscriberBuffer is the channel capacity for each subscriber
+	subscriberBuffer = 256
+)
+
+// Broker fans out cluster events to subscribers. It keeps the events of the
+// most recent action on each cluster so subscribers that connect after an
+// action started still see its full output.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan state.ClusterEvent]struct{}
+	history     map[string][]state.ClusterEvent
+}
+
+// NewBroker creates a new event broker
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string]map[chan state.ClusterEvent]struct{}),
+		history:     make(map[string][]state.ClusterEvent),
+	}
+}
+
+// Publish sends an event to all subscribers of the event's cluster. A
+// started event resets the cluster's history. Slow subscribers miss events
+// rather than blocking the publisher.
+func (b *Broker) Publish(event state.ClusterEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.Type == state.EventStarted {
+		b.history[event.Cluster] = nil
+	}
+	history := append(b.history[event.Cluster], event)
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	b.history[event.Cluster] = history
+
+	for ch := range b.subscribers[event.Cluster] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe registers for events on a cluster. It returns the events of the
+// cluster's most recent action, a channel of subsequent events and a function
+// that must be called to unsubscribe.
+func (b *Broker) Subscribe(cluster string) ([]state.ClusterEvent, <-chan state.ClusterEvent, func()) {
+	ch := make(chan state.ClusterEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[cluster] == nil {
+		b.subscribers[cluster] = make(map[chan state.ClusterEvent]struct{})
+	}
+	b.subscribers[cluster][ch] = struct{}{}
+
+	history := make([]state.ClusterEvent, len(b.history[cluster]))
+	copy(history, b.history[cluster])
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[cluster], ch)
+		if len(b.subscribers[cluster]) == 0 {
+			delete(b.subscribers, cluster)
+		}
+	}
+
+	return history, ch, unsubscribe
+}
+
+// Forget drops the history of a cluster that no longer exists. Current
+// subscribers are unaffected.
+func (b *Broker) Forget(cluster string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.history, cluster)
+}
+
+// LineWriter is an io.Writer that publishes each complete line written to it
+// as an output event
+type LineWriter struct {
+	broker    *Broker
+	cluster   string
+	action    string
+	operation string
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// NewLineWriter returns a writer publishing output lines for an action on a cluster
+func (b *Broker) NewLineWriter(cluster, action, operation string) *LineWriter {
+	return &LineWriter{
+		broker:    b,
+		cluster:   cluster,
+		action:    action,
+		operation: operation,
+	}
+}
+
+// Write buffers p and publishes every complete line
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(w.buf.Next(idx + 1))
+		w.publish(line)
+	}
+	return len(p), nil
+}
+
+// Flush publishes any trailing partial line
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() > 0 {
+		w.publish(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+// publish sends a single output line. The caller must hold w.mu.
+func (w *LineWriter) publish(line string) {
+	w.broker.Publish(state.ClusterEvent{
+		Type:      state.EventOutput,
+		Cluster:   w.cluster,
+		Action:    w.action,
+		Operation: w.operation,
+		Message:   strings.TrimRight(line, "\r\n"),
+	})
+}
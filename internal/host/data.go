@@ -0,0 +1,128 @@
+package host
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ClusterDataRoot holds one data directory per cluster. It is a directory
+// of its own inside /root/kind, which earlier versions mounted into every
+// cluster and may still hold shared data.
+const ClusterDataRoot = "/root/kind/clusters"
+
+// RetainedDataRoot holds the data directories of deleted clusters whose data
+// was retained
+const RetainedDataRoot = "/root/kind-retained"
+
+// btrfsMagic is the statfs filesystem type of btrfs
+const btrfsMagic = 0x9123683e
+
+// dataRoot is ClusterDataRoot; tests replace it
+var dataRoot = ClusterDataRoot
+
+// CreateClusterDir creates the data directory of a cluster and a
+// subdirectory for each mount. On the btrfs NVMe filesystem the directory
+// is a subvolume so it can be removed or snapshotted on its own. An existing
+// directory is only reused if it is recorded, the directory state holds for
+// the cluster; any other is refused, since it may hold data of another
+// cluster that a delete would remove.
+func CreateClusterDir(cluster string, subdirs []string, nvme bool, recorded string) (dir string, subvolume bool, err error) {
+	dir = filepath.Join(dataRoot, cluster)
+	if strings.ContainsRune(cluster, filepath.Separator) || !childOf(dataRoot, dir) {
+		return "", false, fmt.Errorf("invalid cluster name %q for a data directory", cluster)
+	}
+	if err := os.MkdirAll(dataRoot, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create %s: %w", dataRoot, err)
+	}
+
+	_, err = os.Lstat(dir)
+	if err == nil && filepath.Clean(recorded) != dir {
+		return "", false, fmt.Errorf("data directory %s already exists but is not recorded for cluster %s; move it aside first", dir, cluster)
+	}
+	if os.IsNotExist(err) && nvme && isBtrfs(dataRoot) {
+		if output, err := exec.Command("btrfs", "subvolume", "create", dir).CombinedOutput(); err != nil {
+			return "", false, fmt.Errorf("failed to create subvolume %s: %w: %s", dir, err, output)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create cluster data directory: %w", err)
+	}
+	subvolume = isSubvolume(dir)
+
+	for _, subdir := range subdirs {
+		path := filepath.Join(dir, subdir)
+		if !childOf(dir, path) {
+			return "", false, fmt.Errorf("invalid mount directory %q", subdir)
+		}
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return "", false, fmt.Errorf("failed to create cluster data directory: %w", err)
+		}
+	}
+	return dir, subvolume, nil
+}
+
+// RemoveClusterDir deletes a cluster data directory, which must lie directly
+// in ClusterDataRoot
+func RemoveClusterDir(dir string, subvolume bool) error {
+	if !childOf(dataRoot, dir) {
+		return fmt.Errorf("refusing to remove %s: not a cluster data directory", dir)
+	}
+	if subvolume {
+		if output, err := exec.Command("btrfs", "subvolume", "delete", dir).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to delete subvolume %s: %w: %s", dir, err, output)
+		}
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+	return nil
+}
+
+// RetainClusterDir moves a cluster data directory aside so the cluster name
+// can be reused, returning where the data now lives
+func RetainClusterDir(cluster, dir string) (string, error) {
+	if !childOf(dataRoot, dir) {
+		return "", fmt.Errorf("refusing to retain %s: not a cluster data directory", dir)
+	}
+	retained := filepath.Join(RetainedDataRoot, cluster+"-"+time.Now().Format("20060102-150405"))
+	if strings.ContainsRune(cluster, filepath.Separator) || !childOf(RetainedDataRoot, retained) {
+		return "", fmt.Errorf("invalid cluster name %q for retained data", cluster)
+	}
+
+	if err := os.MkdirAll(RetainedDataRoot, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", RetainedDataRoot, err)
+	}
+	if err := os.Rename(dir, retained); err != nil {
+		return "", fmt.Errorf("failed to retain %s: %w", dir, err)
+	}
+	return retained, nil
+}
+
+// childOf reports whether path, once cleaned, names an entry directly in
+// root. Paths that climb out of root with ".." or are root itself are not.
+func childOf(root, path string) bool {
+	return filepath.Dir(filepath.Clean(path)) == root
+}
+
+// isBtrfs reports whether path is on a btrfs filesystem
+func isBtrfs(path string) bool {
+	var st syscall.Statfs_t
+	return syscall.Statfs(path, &st) == nil && st.Type == btrfsMagic
+}
+
+// isSubvolume reports whether dir is the root of a btrfs subvolume, whose
+// inode number is always 256
+func isSubvolume(dir string) bool {
+	info, err := os.Stat(dir)
+	if err != nil || !isBtrfs(dir) {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Ino == 256
+}
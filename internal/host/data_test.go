@@ -0,0 +1,93 @@
+package host
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClusterDirRejectsEscapingNames(t *testing.T) {
+	for _, name := range []string{"..", "a/b", "/etc", "", "."} {
+		if _, _, err := CreateClusterDir(name, nil, false, ""); err == nil {
+			t.Errorf("CreateClusterDir(%q) succeeded", name)
+		}
+	}
+	for _, name := range []string{"../..", "a/b", "/etc"} {
+		if _, err := RetainClusterDir(name, filepath.Join(ClusterDataRoot, "c")); err == nil {
+			t.Errorf("RetainClusterDir(%q) succeeded", name)
+		}
+	}
+}
+
+func TestRemoveClusterDirRejectsPathsOutsideRoot(t *testing.T) {
+	for _, dir := range []string{
+		filepath.Join(ClusterDataRoot, ".."),
+		filepath.Join(ClusterDataRoot, "a/b"),
+		ClusterDataRoot + "/../etc",
+		"/etc",
+		ClusterDataRoot,
+		"",
+	} {
+		if err := RemoveClusterDir(dir, false); err == nil {
+			t.Errorf("RemoveClusterDir(%q) succeeded", dir)
+		}
+		if _, err := RetainClusterDir("c", dir); err == nil {
+			t.Errorf("RetainClusterDir(%q) succeeded", dir)
+		}
+	}
+}
+
+func TestChildOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/root/kind/dev", true},
+		{"/root/kind/dev/", true},
+		{"/root/kind/./dev", true},
+		{"/root/kind", false},
+		{"/root/kind/..", false},
+		{"/root/kind/a/b", false},
+		{"/root/kind/../kind2", false},
+		{"/etc", false},
+	}
+	for _, tt := range tests {
+		if got := childOf("/root/kind", tt.path); got != tt.want {
+			t.Errorf("childOf(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCreateClusterDirRefusesUnrecordedDirectory(t *testing.T) {
+	old := dataRoot
+	t.Cleanup(func() { dataRoot = old })
+	dataRoot = t.TempDir()
+
+	existing := filepath.Join(dataRoot, "shared")
+	if err := os.MkdirAll(existing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := CreateClusterDir("shared", nil, false, ""); err == nil || !strings.Contains(err.Error(), "not recorded") {
+		t.Errorf("CreateClusterDir over an unrecorded directory = %v, want a refusal", err)
+	}
+	if _, _, err := CreateClusterDir("shared", nil, false, filepath.Join(dataRoot, "other")); err == nil {
+		t.Error("CreateClusterDir reused a directory recorded for another path")
+	}
+
+	dir, _, err := CreateClusterDir("shared", []string{"local"}, false, existing)
+	if err != nil || dir != existing {
+		t.Errorf("CreateClusterDir with the recorded directory = %q, %v", dir, err)
+	}
+	if _, err := os.Stat(filepath.Join(existing, "local")); err != nil {
+		t.Errorf("mount subdirectory not created: %v", err)
+	}
+
+	dir, _, err = CreateClusterDir("fresh", nil, false, "")
+	if err != nil || dir != filepath.Join(dataRoot, "fresh") {
+		t.Errorf("CreateClusterDir(fresh) = %q, %v", dir, err)
+	}
+	if err := RemoveClusterDir(dir, false); err != nil {
+		t.Errorf("RemoveClusterDir(%s) = %v", dir, err)
+	}
+}
@@ -3,6 +3,8 @@ package host
 import (
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 
 	"github.com/kylape/host-manager/internal/kind"
 	"github.com/kylape/host-manager/internal/state"
@@ -61,7 +63,7 @@ func (m *Manager) Initialize() error {
 
 	// Create base infrastructure
 	log.Println("Creating base infrastructure...")
-	if err := m.createBaseInfrastructure(); err != nil {
+	if err := m.createBaseInfrastructure(storage.HasNVMe); err != nil {
 		return fmt.Errorf("failed to create base infrastructure: %w", err)
 	}
 
@@ -94,12 +96,12 @@ func (m *Manager) configureSSH() error {
 }
 
 // createBaseInfrastructure creates the base kind cluster and registry
-func (m *Manager) createBaseInfrastructure() error {
+func (m *Manager) createBaseInfrastructure(nvme bool) error {
 	kindClient := kind.NewClient()
 
 	// Create shared registry
 	log.Println("Creating shared container registry...")
-	if err := kindClient.CreateRegistry(); err != nil {
+	if err := kindClient.CreateRegistry(os.Stdout); err != nil {
 		return fmt.Errorf("failed to create registry: %w", err)
 	}
 
@@ -109,11 +111,73 @@ func (m *Manager) createBaseInfrastructure() error {
 
 	// Create base infrastructure cluster
 	log.Println("Creating base infrastructure cluster...")
-	if err := kindClient.CreateCluster("kind", true); err != nil {
+	image, err := kind.ResolveNodeImage("", "", false)
+	if err != nil {
+		return err
+	}
+
+	// Record the cluster before creating it so its host ports are allocated;
+	// a failed earlier bootstrap may have left a record behind
+	ports, err := state.ClusterPorts(nil)
+	if err != nil {
+		return err
+	}
+	hostState, err := m.stateManager.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load host state: %w", err)
+	}
+	previous := hostState.Clusters["kind"]
+	if err := m.stateManager.RemoveCluster("kind"); err != nil {
+		return fmt.Errorf("failed to update cluster state: %w", err)
+	}
+	dir, subvolume, err := CreateClusterDir("kind", []string{state.LocalMount.Name}, nvme, previous.DataDir)
+	if err != nil {
+		return err
+	}
+	local := state.LocalMount
+	local.HostPath = filepath.Join(dir, local.Name)
+	info := state.ClusterInfo{
+		Type:              "infrastructure",
+		KubernetesVersion: image.Version,
+		NodeImage:         image.Image,
+		Registry:          true,
+		Ports:             ports,
+		DataDir:           dir,
+		DataSubvolume:     subvolume,
+		DataPolicy:        state.DataRetain,
+		Mounts:            []state.Mount{local},
+	}
+	if err := m.stateManager.AddCluster("kind", info); err != nil {
+		return fmt.Errorf("failed to update cluster state: %w", err)
+	}
+	err = m.stateManager.ModifyCluster("kind", func(recorded *state.ClusterInfo) error {
+		info = *recorded
+		return recorded.Transition("kind", state.StatusCreating, "")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update cluster state: %w", err)
+	}
+
+	opts := kind.CreateOptions{
+		Registry:  true,
+		NodeImage: image.Image,
+		Mounts:    []kind.Mount{{HostPath: local.HostPath, ContainerPath: local.ContainerPath}},
+	}
+	for _, mapping := range info.Ports {
+		if mapping.Purpose == state.PortAPIServer {
+			opts.APIServerPort = mapping.HostPort
+		} else {
+			opts.PortMappings = append(opts.PortMappings, kind.PortMapping{ContainerPort: mapping.ContainerPort, HostPort: mapping.HostPort})
+		}
+	}
+	if err := kindClient.CreateCluster("kind", opts, os.Stdout); err != nil {
+		if err := m.stateManager.TransitionCluster("kind", state.StatusFailed, "bootstrap create failed"); err != nil {
+			log.Printf("Failed to record base cluster failure: %v", err)
+		}
 		return fmt.Errorf("failed to create base cluster: %w", err)
 	}
 
-	if err := m.stateManager.UpdateCluster("kind", "running", "infrastructure", false); err != nil {
+	if err := m.stateManager.TransitionCluster("kind", state.StatusReady, ""); err != nil {
 		return fmt.Errorf("failed to update cluster state: %w", err)
 	}
 
@@ -0,0 +1,30 @@
+package host
+
+import (
+	"fmt"
+	"os"
+)
+
+// KVMDevice is passed into the nodes of KubeVirt clusters
+const KVMDevice = "/dev/kvm"
+
+// CheckKVM verifies the host can run KubeVirt virtual machines with hardware
+// acceleration. On EC2 /dev/kvm exists only on bare-metal instances and on
+// instances with nested virtualization enabled; opening it proves the
+// kernel's KVM module is usable.
+func CheckKVM() error {
+	info, err := os.Stat(KVMDevice)
+	if err != nil {
+		return fmt.Errorf("%s not found; use a bare-metal instance or enable nested virtualization", KVMDevice)
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("%s is not a character device", KVMDevice)
+	}
+
+	f, err := os.OpenFile(KVMDevice, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", KVMDevice, err)
+	}
+	f.Close()
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/kylape/host-manager/internal/state"
 )
@@ -100,8 +101,8 @@ func setupNVMeStorage(device string) error {
 	}
 
 	// Create required directories
-	if err := os.MkdirAll("/root/kind", 0755); err != nil {
-		return fmt.Errorf("failed to create /root/kind: %w", err)
+	if err := os.MkdirAll(ClusterDataRoot, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", ClusterDataRoot, err)
 	}
 
 	if err := os.MkdirAll("/root/containers/storage", 0755); err != nil {
@@ -150,4 +151,28 @@ runroot = "/run/containers/storage"
 	cmd.Run() // Ignore errors
 
 	return nil
-}
\ No newline at end of file
+}
+
+// DiskUsage reports capacity and free space of the filesystem on device. When
+// device is empty or not mounted, the root filesystem is reported instead.
+func DiskUsage(device string) (mountpoint string, total, available uint64, err error) {
+	mountpoint = "/"
+	if device != "" {
+		if mounts, readErr := ioutil.ReadFile("/proc/mounts"); readErr == nil {
+			for _, line := range strings.Split(string(mounts), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) >= 2 && fields[0] == device {
+					mountpoint = fields[1]
+					break
+				}
+			}
+		}
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mountpoint, &st); err != nil {
+		return mountpoint, 0, 0, fmt.Errorf("failed to stat filesystem at %s: %w", mountpoint, err)
+	}
+
+	return mountpoint, uint64(st.Blocks) * uint64(st.Bsize), uint64(st.Bavail) * uint64(st.Bsize), nil
+}
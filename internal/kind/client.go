@@ -1,42 +1,87 @@
 package kind
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 )
 
+// ErrClusterNotFound is returned when kind does not know a cluster
+var ErrClusterNotFound = errors.New("cluster not found")
+
+// CommandObserver is notified after every kind or podman command the client runs
+type CommandObserver func(command, subcommand string, duration time.Duration, err error)
+
+// abortGrace is how long an aborted command has to exit after SIGTERM
+// before it is killed
+const abortGrace = 10 * time.Second
+
 // Client wraps kind CLI operations
-type Client struct{}
+type Client struct {
+	observer CommandObserver
+
+	// ctx is cancelled by Abort, which kills the commands it started
+	ctx   context.Context
+	abort context.CancelFunc
+}
 
 // NewClient creates a new kind client
 func NewClient() *Client {
-	return &Client{}
+	ctx, abort := context.WithCancel(context.Background())
+	return &Client{ctx: ctx, abort: abort}
 }
 
-// CreateCluster creates a new kind cluster
-func (c *Client) CreateCluster(name string, withRegistry bool) error {
-	var config string
-	if withRegistry {
-		config = c.getClusterConfigWithRegistry()
-	} else {
-		config = c.getBasicClusterConfig()
-	}
+// Abort terminates every running command along with the processes it
+// started, and makes later commands fail immediately. Commands that are
+// aborted return once they have exited.
+func (c *Client) Abort() {
+	c.abort()
+}
 
-	cmd := exec.Command("kind", "create", "cluster", "--name", name, "--config", "-")
-	cmd.Stdin = strings.NewReader(config)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// Aborted returns a channel that is closed once Abort has been called
+func (c *Client) Aborted() <-chan struct{} {
+	return c.ctx.Done()
+}
 
-	output, err := cmd.CombinedOutput()
+// SetObserver registers a function notified of every command execution
+func (c *Client) SetObserver(observer CommandObserver) {
+	c.observer = observer
+}
+
+// CreateOptions controls how a cluster is created
+type CreateOptions struct {
+	Registry      bool          // connect the cluster to the shared registry
+	Retain        bool          // keep node containers if kind fails, for debugging
+	NodeImage     string        // node image to run; kind's default if empty
+	Nodes         []NodeConfig  // nodes to create; a single control plane if empty
+	APIServerPort int           // host port for the API server; random if 0
+	PortMappings  []PortMapping // host ports forwarded to the first control-plane node
+	Mounts        []Mount       // host directories mounted into every node
+}
+
+// CreateCluster creates a new kind cluster, writing kind's progress output to out
+func (c *Client) CreateCluster(name string, opts CreateOptions, out io.Writer) error {
+	config := renderConfig(opts)
+
+	args := []string{"create", "cluster", "--name", name, "--config", "-"}
+	if opts.Retain {
+		args = append(args, "--retain")
+	}
+
+	output, err := c.runCommand(out, strings.NewReader(config), "kind", args...)
 	if err != nil {
 		return fmt.Errorf("failed to create cluster %s: %w\nOutput: %s", name, err, string(output))
 	}
 
 	// Connect to registry if it exists and this cluster should use it
-	if withRegistry {
-		if err := c.connectToRegistry(name); err != nil {
+	if opts.Registry {
+		if err := c.connectToRegistry(name, out); err != nil {
 			return fmt.Errorf("failed to connect cluster to registry: %w", err)
 		}
 	}
@@ -44,22 +89,160 @@ func (c *Client) CreateCluster(name string, withRegistry bool) error {
 	return nil
 }
 
-// DeleteCluster deletes a kind cluster
-func (c *Client) DeleteCluster(name string) error {
-	cmd := exec.Command("kind", "delete", "cluster", "--name", name)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	output, err := cmd.CombinedOutput()
+// DeleteCluster deletes a kind cluster, writing kind's output to out
+func (c *Client) DeleteCluster(name string, out io.Writer) error {
+	output, err := c.runCommand(out, nil, "kind", "delete", "cluster", "--name", name)
 	if err != nil {
 		return fmt.Errorf("failed to delete cluster %s: %w\nOutput: %s", name, err, string(output))
 	}
 	return nil
 }
 
+// nodeOrder ranks node roles in the order their containers are started:
+// control planes before the load balancer in front of them, workers last.
+// Containers are stopped in the reverse order.
+var nodeOrder = map[string]int{"control-plane": 0, "external-load-balancer": 1, "worker": 2}
+
+// clusterNodes returns the node containers of a cluster in start order
+func (c *Client) clusterNodes(name string) ([]Node, error) {
+	nodes, err := c.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes[name]) == 0 {
+		return nil, fmt.Errorf("cluster %s has no node containers", name)
+	}
+
+	ordered := append([]Node(nil), nodes[name]...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return nodeOrder[ordered[i].Role] < nodeOrder[ordered[j].Role]
+	})
+	return ordered, nil
+}
+
+// StopCluster stops the node containers of a cluster, workers first,
+// writing podman's output to out. The containers and their data are kept.
+func (c *Client) StopCluster(name string, out io.Writer) error {
+	nodes, err := c.clusterNodes(name)
+	if err != nil {
+		return err
+	}
+
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if nodes[i].Paused {
+			// A frozen container cannot handle the stop signal
+			if output, err := c.runCommand(out, nil, "podman", "unpause", nodes[i].Name); err != nil {
+				return fmt.Errorf("failed to unpause node %s: %w\nOutput: %s", nodes[i].Name, err, string(output))
+			}
+		} else if !nodes[i].Running {
+			continue
+		}
+		output, err := c.runCommand(out, nil, "podman", "stop", nodes[i].Name)
+		if err != nil {
+			return fmt.Errorf("failed to stop node %s: %w\nOutput: %s", nodes[i].Name, err, string(output))
+		}
+	}
+	return nil
+}
+
+// StartCluster starts the node containers of a cluster, control planes
+// first, and reattaches the shared registry to the kind network. Paused
+// containers are unpaused. It does not wait for Kubernetes to come up.
+func (c *Client) StartCluster(name string, out io.Writer) error {
+	nodes, err := c.clusterNodes(name)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if node.Running {
+			continue
+		}
+		action := "start"
+		if node.Paused {
+			action = "unpause"
+		}
+		output, err := c.runCommand(out, nil, "podman", action, node.Name)
+		if err != nil {
+			return fmt.Errorf("failed to start node %s: %w\nOutput: %s", node.Name, err, string(output))
+		}
+	}
+
+	if c.RegistryRunning() {
+		c.runCommand(out, nil, "podman", "network", "connect", "kind", "kind-registry") // Ignore errors - might already be connected
+	}
+	return nil
+}
+
+// PauseCluster freezes the processes of every running node container of a
+// cluster, writing podman's output to out. Memory stays allocated but no CPU
+// is used.
+func (c *Client) PauseCluster(name string, out io.Writer) error {
+	nodes, err := c.clusterNodes(name)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if !node.Running {
+			continue
+		}
+		output, err := c.runCommand(out, nil, "podman", "pause", node.Name)
+		if err != nil {
+			return fmt.Errorf("failed to pause node %s: %w\nOutput: %s", node.Name, err, string(output))
+		}
+	}
+	return nil
+}
+
+// UnpauseCluster resumes the paused node containers of a cluster. Errors
+// of all nodes are returned together.
+func (c *Client) UnpauseCluster(name string, out io.Writer) error {
+	nodes, err := c.clusterNodes(name)
+	if err != nil {
+		return err
+	}
+
+	// Every node is tried so one failure leaves no other node frozen
+	var errs []error
+	for _, node := range nodes {
+		if !node.Paused {
+			continue
+		}
+		output, err := c.runCommand(out, nil, "podman", "unpause", node.Name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to unpause node %s: %w\nOutput: %s", node.Name, err, string(output)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ExportLogs writes the logs of a cluster's nodes to dir
+func (c *Client) ExportLogs(name, dir string, out io.Writer) error {
+	output, err := c.runCommand(out, nil, "kind", "export", "logs", dir, "--name", name)
+	if err != nil {
+		return fmt.Errorf("failed to export logs of cluster %s: %w\nOutput: %s", name, err, string(output))
+	}
+	return nil
+}
+
+// ClusterExists reports whether kind knows a cluster named name
+func (c *Client) ClusterExists(name string) (bool, error) {
+	clusters, err := c.ListClusters()
+	if err != nil {
+		return false, err
+	}
+	for _, cluster := range clusters {
+		if cluster == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // ListClusters returns a list of kind clusters
 func (c *Client) ListClusters() ([]string, error) {
-	cmd := exec.Command("kind", "get", "clusters")
-	output, err := cmd.Output()
+	output, err := c.commandOutput("kind", "get", "clusters")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list clusters: %w", err)
 	}
@@ -72,48 +255,133 @@ func (c *Client) ListClusters() ([]string, error) {
 	return clusters, nil
 }
 
+// Node is a kind node container
+type Node struct {
+	Name    string `json:"name"`
+	Cluster string `json:"cluster"`
+	Role    string `json:"role"` // "control-plane", "worker" or "external-load-balancer"
+	Running bool   `json:"running"`
+	Paused  bool   `json:"paused"` // frozen by podman pause; neither running nor stopped
+}
+
+// ListNodes returns the kind node containers known to podman, including
+// stopped ones, grouped by cluster
+func (c *Client) ListNodes() (map[string][]Node, error) {
+	output, err := c.commandOutput("podman", "ps", "-a",
+		"--filter", "label=io.x-k8s.kind.cluster",
+		"--format", "{{index .Labels \"io.x-k8s.kind.cluster\"}}\t{{.Names}}\t{{.State}}\t{{index .Labels \"io.x-k8s.kind.role\"}}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node containers: %w", err)
+	}
+
+	nodes := make(map[string][]Node)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		nodes[fields[0]] = append(nodes[fields[0]], Node{
+			Name:    fields[1],
+			Cluster: fields[0],
+			Role:    fields[3],
+			Running: strings.EqualFold(fields[2], "running"),
+			Paused:  strings.EqualFold(fields[2], "paused"),
+		})
+	}
+
+	return nodes, nil
+}
+
+// ClusterDetails describes an existing kind cluster as found on the host
+type ClusterDetails struct {
+	Name              string
+	Nodes             []Node
+	NodeImage         string
+	KubernetesVersion string
+	Registry          bool // nodes are configured to pull from the shared registry
+	Created           *time.Time
+}
+
+// InspectCluster examines an existing kind cluster's nodes, node image,
+// Kubernetes version and registry configuration
+func (c *Client) InspectCluster(name string) (*ClusterDetails, error) {
+	exists, err := c.ClusterExists(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrClusterNotFound
+	}
+
+	nodes, err := c.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	details := &ClusterDetails{Name: name, Nodes: nodes[name]}
+
+	var controlPlane *Node
+	for i, node := range details.Nodes {
+		if node.Role == "control-plane" && controlPlane == nil {
+			controlPlane = &details.Nodes[i]
+		}
+	}
+	if controlPlane == nil {
+		return nil, fmt.Errorf("cluster %s has no control-plane node container", name)
+	}
+
+	output, err := c.commandOutput("podman", "inspect", "--format", "{{.ImageName}}\t{{.Created.Format \"2006-01-02T15:04:05Z07:00\"}}", controlPlane.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect node %s: %w", controlPlane.Name, err)
+	}
+	if fields := strings.Split(strings.TrimSpace(string(output)), "\t"); len(fields) == 2 {
+		details.NodeImage = fields[0]
+		if created, err := time.Parse(time.RFC3339, fields[1]); err == nil {
+			details.Created = &created
+		}
+	}
+
+	// The version and registry wiring can only be read from a running node
+	if controlPlane.Running {
+		if output, err := c.commandOutput("podman", "exec", controlPlane.Name, "kubelet", "--version"); err == nil {
+			details.KubernetesVersion = strings.TrimPrefix(strings.TrimSpace(string(output)), "Kubernetes ")
+		}
+		_, err := c.commandOutput("podman", "exec", controlPlane.Name, "test", "-f", "/etc/containerd/certs.d/localhost:5001/hosts.toml")
+		details.Registry = err == nil
+	}
+
+	return details, nil
+}
+
 // GetKubeconfig returns the kubeconfig for a cluster
 func (c *Client) GetKubeconfig(name string) (string, error) {
-	cmd := exec.Command("kind", "get", "kubeconfig", "--name", name)
-	output, err := cmd.Output()
+	output, err := c.commandOutput("kind", "get", "kubeconfig", "--name", name)
 	if err != nil {
 		return "", fmt.Errorf("failed to get kubeconfig for %s: %w", name, err)
 	}
 	return string(output), nil
 }
 
-// CreateRegistry creates the shared container registry
-func (c *Client) CreateRegistry() error {
+// CreateRegistry creates the shared container registry, writing podman's output to out
+func (c *Client) CreateRegistry(out io.Writer) error {
 	// Check if registry already exists
-	cmd := exec.Command("podman", "inspect", "kind-registry")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if cmd.Run() == nil {
+	if _, err := c.runCommand(out, nil, "podman", "inspect", "kind-registry"); err == nil {
 		// Registry already exists, check if it's running
-		cmd = exec.Command("podman", "inspect", "-f", "{{.State.Running}}", "kind-registry")
-		output, err := cmd.Output()
-		if err == nil && strings.TrimSpace(string(output)) == "true" {
+		if c.RegistryRunning() {
 			return nil // Registry is already running
 		}
 
 		// Start existing registry
-		cmd = exec.Command("podman", "start", "kind-registry")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		return cmd.Run()
+		_, err = c.runCommand(out, nil, "podman", "start", "kind-registry")
+		return err
 	}
 
 	// Create new registry
-	cmd = exec.Command("podman", "run",
+	output, err := c.runCommand(out, nil, "podman", "run",
 		"-d", "--restart=always",
 		"-p", "127.0.0.1:5001:5000",
 		"--network", "bridge",
 		"--name", "kind-registry",
 		"registry:2")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to create registry: %w\nOutput: %s", err, string(output))
 	}
@@ -121,63 +389,37 @@ func (c *Client) CreateRegistry() error {
 	return nil
 }
 
-// LoadImage loads a Docker image into a cluster
-func (c *Client) LoadImage(clusterName, imageName string) error {
-	cmd := exec.Command("kind", "load", "docker-image", imageName, "--name", clusterName)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	output, err := cmd.CombinedOutput()
+// RegistryRunning reports whether the shared registry container is running
+func (c *Client) RegistryRunning() bool {
+	output, err := c.commandOutput("podman", "inspect", "-f", "{{.State.Running}}", "kind-registry")
+	return err == nil && strings.TrimSpace(string(output)) == "true"
+}
+
+// LoadImage loads a Docker image into a cluster, writing kind's output to out
+func (c *Client) LoadImage(clusterName, imageName string, out io.Writer) error {
+	output, err := c.runCommand(out, nil, "kind", "load", "docker-image", imageName, "--name", clusterName)
 	if err != nil {
 		return fmt.Errorf("failed to load image %s into cluster %s: %w\nOutput: %s", imageName, clusterName, err, string(output))
 	}
 	return nil
 }
 
-// getClusterConfigWithRegistry returns kind config that connects to the shared registry
-func (c *Client) getClusterConfigWithRegistry() string {
-	return `kind: Cluster
-apiVersion: kind.x-k8s.io/v1alpha4
-containerdConfigPatches:
-- |-
-  [plugins."io.containerd.grpc.v1.cri".registry]
-    config_path = "/etc/containerd/certs.d"
-kubeadmConfigPatches:
-- |
-  apiVersion: kubeadm.k8s.io/v1
-  kind: ClusterConfiguration
-  metadata:
-    name: config
-  kubernetesVersion: "v1.32.0"
-nodes:
-- role: control-plane
-  extraPortMappings:
-  - containerPort: 32222
-    hostPort: 2222
-  extraMounts:
-  - containerPath: /local
-    hostPath: /root/kind`
-}
-
-// getBasicClusterConfig returns a basic kind config
-func (c *Client) getBasicClusterConfig() string {
-	return `kind: Cluster
-apiVersion: kind.x-k8s.io/v1alpha4
-kubeadmConfigPatches:
-- |
-  apiVersion: kubeadm.k8s.io/v1
-  kind: ClusterConfiguration
-  metadata:
-    name: config
-  kubernetesVersion: "v1.32.0"
-nodes:
-- role: control-plane`
+// Kubectl runs kubectl against a cluster from inside its first control-plane
+// node, so the host needs neither kubectl nor a kubeconfig
+func (c *Client) Kubectl(clusterName string, stdin io.Reader, out io.Writer, args ...string) error {
+	node := clusterName + "-control-plane"
+	cmdArgs := append([]string{"exec", "-i", node, "kubectl", "--kubeconfig", "/etc/kubernetes/admin.conf"}, args...)
+	output, err := c.runCommand(out, stdin, "podman", cmdArgs...)
+	if err != nil {
+		return fmt.Errorf("kubectl %s failed in cluster %s: %w\nOutput: %s", strings.Join(args, " "), clusterName, err, string(output))
+	}
+	return nil
 }
 
 // connectToRegistry connects a cluster to the shared registry
-func (c *Client) connectToRegistry(clusterName string) error {
+func (c *Client) connectToRegistry(clusterName string, out io.Writer) error {
 	// Get cluster nodes
-	cmd := exec.Command("kind", "get", "nodes", "--name", clusterName)
-	output, err := cmd.Output()
+	output, err := c.commandOutput("kind", "get", "nodes", "--name", clusterName)
 	if err != nil {
 		return fmt.Errorf("failed to get cluster nodes: %w", err)
 	}
@@ -191,29 +433,74 @@ func (c *Client) connectToRegistry(clusterName string) error {
 		}
 
 		// Create registry config directory
-		cmd = exec.Command("podman", "exec", node, "mkdir", "-p", "/etc/containerd/certs.d/localhost:5001")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
+		if _, err := c.runCommand(out, nil, "podman", "exec", node, "mkdir", "-p", "/etc/containerd/certs.d/localhost:5001"); err != nil {
 			return fmt.Errorf("failed to create registry config dir in node %s: %w", node, err)
 		}
 
 		// Write registry config
 		config := "[host.\"http://kind-registry:5000\"]"
-		cmd = exec.Command("podman", "exec", "-i", node, "cp", "/dev/stdin", "/etc/containerd/certs.d/localhost:5001/hosts.toml")
-		cmd.Stdin = strings.NewReader(config)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
+		if _, err := c.runCommand(out, strings.NewReader(config), "podman", "exec", "-i", node, "cp", "/dev/stdin", "/etc/containerd/certs.d/localhost:5001/hosts.toml"); err != nil {
 			return fmt.Errorf("failed to write registry config in node %s: %w", node, err)
 		}
 	}
 
 	// Connect registry to cluster network
-	cmd = exec.Command("podman", "network", "connect", "kind", "kind-registry")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Run() // Ignore errors - might already be connected
+	c.runCommand(out, nil, "podman", "network", "connect", "kind", "kind-registry") // Ignore errors - might already be connected
 
 	return nil
-}
\ No newline at end of file
+}
+
+// runCommand runs a command with the given stdin, copying its combined output
+// to out (if non-nil) as it is produced. The captured output is also returned
+// so callers can include it in error messages.
+func (c *Client) runCommand(out io.Writer, stdin io.Reader, name string, args ...string) ([]byte, error) {
+	var output bytes.Buffer
+	var w io.Writer = &output
+	if out != nil {
+		w = io.MultiWriter(&output, out)
+	}
+
+	cmd := c.command(name, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	start := time.Now()
+	err := cmd.Run()
+	c.observe(name, args, start, err)
+	return output.Bytes(), err
+}
+
+// commandOutput runs a command and returns its standard output
+func (c *Client) commandOutput(name string, args ...string) ([]byte, error) {
+	start := time.Now()
+	output, err := c.command(name, args...).Output()
+	c.observe(name, args, start, err)
+	return output, err
+}
+
+// command prepares a command that Abort terminates. It runs in a process
+// group of its own so that the podman processes kind starts are terminated
+// with it.
+func (c *Client) command(name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(c.ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return terminateProcessGroup(cmd)
+	}
+	cmd.WaitDelay = abortGrace
+	return cmd
+}
+
+// observe reports a finished command to the observer, if any
+func (c *Client) observe(name string, args []string, start time.Time, err error) {
+	if c.observer == nil {
+		return
+	}
+
+	subcommand := ""
+	if len(args) > 0 {
+		subcommand = args[0]
+	}
+	c.observer(name, subcommand, time.Since(start), err)
+}
@@ -0,0 +1,130 @@
+package kind
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NodeConfig describes one node of a cluster to create
+type NodeConfig struct {
+	Role   string // "control-plane" or "worker"
+	Labels map[string]string
+	Taints []Taint
+}
+
+// PortMapping forwards a host port to a port of a node container
+type PortMapping struct {
+	ContainerPort int
+	HostPort      int
+}
+
+// Mount makes a host directory available in a node container
+type Mount struct {
+	HostPath      string
+	ContainerPath string
+}
+
+// Taint is a node taint applied when the node registers
+type Taint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+// renderConfig generates the kind cluster configuration for a create. The
+// first control-plane node carries the port mappings; mounts are added to
+// every node so workloads see the same data wherever they are scheduled.
+func renderConfig(opts CreateOptions) string {
+	nodes := opts.Nodes
+	if len(nodes) == 0 {
+		nodes = []NodeConfig{{Role: "control-plane"}}
+	}
+
+	var b strings.Builder
+	b.WriteString("kind: Cluster\n")
+	b.WriteString("apiVersion: kind.x-k8s.io/v1alpha4\n")
+
+	if opts.Registry {
+		b.WriteString("containerdConfigPatches:\n")
+		b.WriteString("- |-\n")
+		b.WriteString("  [plugins.\"io.containerd.grpc.v1.cri\".registry]\n")
+		b.WriteString("    config_path = \"/etc/containerd/certs.d\"\n")
+	}
+
+	if opts.APIServerPort != 0 {
+		b.WriteString("networking:\n")
+		fmt.Fprintf(&b, "  apiServerPort: %d\n", opts.APIServerPort)
+	}
+
+	b.WriteString("nodes:\n")
+	firstControlPlane := true
+	for _, node := range nodes {
+		fmt.Fprintf(&b, "- role: %s\n", node.Role)
+		if opts.NodeImage != "" {
+			fmt.Fprintf(&b, "  image: %s\n", opts.NodeImage)
+		}
+
+		if len(node.Labels) > 0 {
+			b.WriteString("  labels:\n")
+			for _, key := range sortedKeys(node.Labels) {
+				fmt.Fprintf(&b, "    %s: %s\n", quote(key), quote(node.Labels[key]))
+			}
+		}
+
+		if len(node.Taints) > 0 {
+			// The first control-plane node is initialized; all others join
+			kubeadmKind := "JoinConfiguration"
+			if node.Role == "control-plane" && firstControlPlane {
+				kubeadmKind = "InitConfiguration"
+			}
+			b.WriteString("  kubeadmConfigPatches:\n")
+			b.WriteString("  - |\n")
+			fmt.Fprintf(&b, "    kind: %s\n", kubeadmKind)
+			b.WriteString("    nodeRegistration:\n")
+			b.WriteString("      taints:\n")
+			for _, taint := range node.Taints {
+				fmt.Fprintf(&b, "      - key: %s\n", quote(taint.Key))
+				if taint.Value != "" {
+					fmt.Fprintf(&b, "        value: %s\n", quote(taint.Value))
+				}
+				fmt.Fprintf(&b, "        effect: %s\n", taint.Effect)
+			}
+		}
+
+		if node.Role == "control-plane" && firstControlPlane {
+			firstControlPlane = false
+			if len(opts.PortMappings) > 0 {
+				b.WriteString("  extraPortMappings:\n")
+				for _, mapping := range opts.PortMappings {
+					fmt.Fprintf(&b, "  - containerPort: %d\n", mapping.ContainerPort)
+					fmt.Fprintf(&b, "    hostPort: %d\n", mapping.HostPort)
+				}
+			}
+		}
+
+		if len(opts.Mounts) > 0 {
+			b.WriteString("  extraMounts:\n")
+			for _, mount := range opts.Mounts {
+				fmt.Fprintf(&b, "  - containerPath: %s\n", quote(mount.ContainerPath))
+				fmt.Fprintf(&b, "    hostPath: %s\n", quote(mount.HostPath))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// quote renders s as a YAML double-quoted scalar
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
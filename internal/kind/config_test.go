@@ -0,0 +1,115 @@
+package kind
+
+import "testing"
+
+func TestRenderConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CreateOptions
+		want string
+	}{
+		{
+			name: "default",
+			opts: CreateOptions{},
+			want: `kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+nodes:
+- role: control-plane
+`,
+		},
+		{
+			name: "registry and ports",
+			opts: CreateOptions{
+				Registry:      true,
+				NodeImage:     "kindest/node:v1.31.0",
+				APIServerPort: 6443,
+				PortMappings:  []PortMapping{{ContainerPort: 22, HostPort: 2222}},
+			},
+			want: `kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+containerdConfigPatches:
+- |-
+  [plugins."io.containerd.grpc.v1.cri".registry]
+    config_path = "/etc/containerd/certs.d"
+networking:
+  apiServerPort: 6443
+nodes:
+- role: control-plane
+  image: kindest/node:v1.31.0
+  extraPortMappings:
+  - containerPort: 22
+    hostPort: 2222
+`,
+		},
+		{
+			// Port mappings go to the first control plane only, mounts to
+			// every node; taints patch the init or join configuration
+			name: "topology",
+			opts: CreateOptions{
+				Nodes: []NodeConfig{
+					{Role: "control-plane", Taints: []Taint{{Key: "dedicated", Value: "infra", Effect: "NoSchedule"}}},
+					{Role: "control-plane", Taints: []Taint{{Key: "example.com/spare", Effect: "NoExecute"}}},
+					{Role: "worker", Labels: map[string]string{"zone": "b", "example.com/gpu": "true"}},
+				},
+				PortMappings: []PortMapping{{ContainerPort: 30080, HostPort: 40000}},
+				Mounts:       []Mount{{HostPath: "/root/kind/clusters/dev/data", ContainerPath: "/data"}},
+			},
+			want: `kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+nodes:
+- role: control-plane
+  kubeadmConfigPatches:
+  - |
+    kind: InitConfiguration
+    nodeRegistration:
+      taints:
+      - key: "dedicated"
+        value: "infra"
+        effect: NoSchedule
+  extraPortMappings:
+  - containerPort: 30080
+    hostPort: 40000
+  extraMounts:
+  - containerPath: "/data"
+    hostPath: "/root/kind/clusters/dev/data"
+- role: control-plane
+  kubeadmConfigPatches:
+  - |
+    kind: JoinConfiguration
+    nodeRegistration:
+      taints:
+      - key: "example.com/spare"
+        effect: NoExecute
+  extraMounts:
+  - containerPath: "/data"
+    hostPath: "/root/kind/clusters/dev/data"
+- role: worker
+  labels:
+    "example.com/gpu": "true"
+    "zone": "b"
+  extraMounts:
+  - containerPath: "/data"
+    hostPath: "/root/kind/clusters/dev/data"
+`,
+		},
+		{
+			name: "quoted mount paths",
+			opts: CreateOptions{
+				Mounts: []Mount{{HostPath: "/srv/a \"b\"", ContainerPath: "/mnt/x: y"}},
+			},
+			want: `kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+nodes:
+- role: control-plane
+  extraMounts:
+  - containerPath: "/mnt/x: y"
+    hostPath: "/srv/a \"b\""
+`,
+		},
+	}
+	for _, test := range tests {
+		if got := renderConfig(test.opts); got != test.want {
+			t.Errorf("%s: renderConfig() =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
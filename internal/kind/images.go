@@ -0,0 +1,154 @@
+package kind
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultKubernetesVersion is used when a create request names no version
+const DefaultKubernetesVersion = "v1.32.0"
+
+// NodeImage is a kind node image for a Kubernetes release
+type NodeImage struct {
+	Version string `json:"version"`
+	Image   string `json:"image"`
+}
+
+// nodeImages are the node images published with the kind release installed
+// on hosts (v0.26.0), pinned by digest as its release notes require
+var nodeImages = map[string]string{
+	"v1.32.0":  "kindest/node:v1.32.0@sha256:c48c62eac5da28cdadcf560d1d8616cfa6783b58f0d94cf63ad1bf49600cb027",
+	"v1.31.4":  "kindest/node:v1.31.4@sha256:2cb39f7295fe7eafee0842b1052a599a4fb0f8bcf3f83d96c7f4864c357c6c30",
+	"v1.30.8":  "kindest/node:v1.30.8@sha256:17cd608b3971338d9180b00776cb766c50d0a0b6b904ab4ff52fd3fc5c6369bf",
+	"v1.29.12": "kindest/node:v1.29.12@sha256:62c0672ba99a4afd7396512848d6fc382906b8f33349ae68fb1dbfe549f70dec",
+}
+
+// NodeImages returns the supported versions, newest first
+func NodeImages() []NodeImage {
+	images := make([]NodeImage, 0, len(nodeImages))
+	for version, image := range nodeImages {
+		images = append(images, NodeImage{Version: version, Image: image})
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return compareVersions(images[i].Version, images[j].Version) > 0
+	})
+	return images
+}
+
+// ErrCustomImage is returned for a node image that is not one of the curated
+// images when the caller may not run other images
+var ErrCustomImage = errors.New("node image is not a supported kind node image")
+
+// ResolveNodeImage picks the node image for a create request. version may be
+// a full version ("v1.31.4" or "1.31.4") or a minor release ("1.31"), which
+// selects its newest supported patch. An explicit image must be one of the
+// curated images, given by reference or digest, unless allowCustom is set;
+// either way its version, taken from the image tag or version, must be a
+// supported one and agree with version if both are set. With neither, the
+// default version is used.
+func ResolveNodeImage(version, image string, allowCustom bool) (NodeImage, error) {
+	if version != "" && !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+
+	if image == "" {
+		if version == "" {
+			version = DefaultKubernetesVersion
+		}
+		return supportedImage(version)
+	}
+
+	curated, isCurated := curatedImage(image)
+	tagged := imageVersion(image)
+	if isCurated {
+		tagged = curated.Version
+	}
+	if version != "" && tagged != "" && !matchesVersion(tagged, version) {
+		return NodeImage{}, fmt.Errorf("node image %s does not match Kubernetes version %s", image, version)
+	}
+	if isCurated {
+		return curated, nil
+	}
+
+	if !allowCustom {
+		return NodeImage{}, fmt.Errorf("%w: %s; use kubernetes_version or one of the images listed by /kubernetes-versions", ErrCustomImage, image)
+	}
+	if tagged == "" {
+		tagged = version
+	}
+	if tagged == "" {
+		return NodeImage{}, fmt.Errorf("node image %s has no version tag; set the Kubernetes version", image)
+	}
+	supported, err := supportedImage(tagged)
+	if err != nil {
+		return NodeImage{}, err
+	}
+	return NodeImage{Version: supported.Version, Image: image}, nil
+}
+
+// supportedImage returns the curated image of a version, or of the newest
+// supported patch of a minor release
+func supportedImage(version string) (NodeImage, error) {
+	for _, candidate := range NodeImages() {
+		if matchesVersion(candidate.Version, version) {
+			return candidate, nil
+		}
+	}
+
+	supported := make([]string, 0, len(nodeImages))
+	for _, candidate := range NodeImages() {
+		supported = append(supported, candidate.Version)
+	}
+	return NodeImage{}, fmt.Errorf("unsupported Kubernetes version %s (supported: %s)", version, strings.Join(supported, ", "))
+}
+
+// curatedImage reports whether an image reference names a curated image,
+// by its full reference or its digest, which also admits mirrors of it
+func curatedImage(image string) (NodeImage, bool) {
+	_, digest, _ := strings.Cut(image, "@")
+	for version, curated := range nodeImages {
+		_, curatedDigest, _ := strings.Cut(curated, "@")
+		if image == curated || (digest != "" && digest == curatedDigest) {
+			return NodeImage{Version: version, Image: image}, true
+		}
+	}
+	return NodeImage{}, false
+}
+
+// matchesVersion reports whether full version v is want, or a patch of the
+// minor release want
+func matchesVersion(v, want string) bool {
+	return v == want || strings.HasPrefix(v, want+".")
+}
+
+// imageVersion extracts a "vX.Y.Z" tag from a node image reference
+func imageVersion(image string) string {
+	ref, _, _ := strings.Cut(image, "@")
+	slash := strings.LastIndex(ref, "/")
+	colon := strings.LastIndex(ref, ":")
+	if colon <= slash {
+		return ""
+	}
+	tag := ref[colon+1:]
+	if !strings.HasPrefix(tag, "v") {
+		return ""
+	}
+	return tag
+}
+
+// compareVersions orders "vX.Y.Z" strings numerically
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			return na - nb
+		}
+	}
+	return len(pa) - len(pb)
+}
@@ -0,0 +1,136 @@
+package kind
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIEndpoint is how the host reaches a cluster's API server
+type APIEndpoint struct {
+	Server string
+	TLS    *tls.Config
+}
+
+// kubeconfig is the part of a kubeconfig file needed to reach the API
+// server of its current context
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// ParseKubeconfig extracts the API server address and credentials of the
+// current context from a kubeconfig written by kind, which carries its
+// certificates inline
+func ParseKubeconfig(data string) (*APIEndpoint, error) {
+	var config kubeconfig
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	if config.CurrentContext == "" {
+		return nil, fmt.Errorf("kubeconfig has no current context")
+	}
+
+	var clusterName, userName string
+	found := false
+	for _, context := range config.Contexts {
+		if context.Name == config.CurrentContext {
+			clusterName, userName = context.Context.Cluster, context.Context.User
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("kubeconfig has no context %s", config.CurrentContext)
+	}
+
+	var server, caData, certData, keyData string
+	found = false
+	for _, cluster := range config.Clusters {
+		if cluster.Name == clusterName {
+			server, caData = cluster.Cluster.Server, cluster.Cluster.CertificateAuthorityData
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("kubeconfig has no cluster %s", clusterName)
+	}
+	found = false
+	for _, user := range config.Users {
+		if user.Name == userName {
+			certData, keyData = user.User.ClientCertificateData, user.User.ClientKeyData
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("kubeconfig has no user %s", userName)
+	}
+	if server == "" {
+		return nil, fmt.Errorf("kubeconfig cluster %s has no server", clusterName)
+	}
+
+	decode := func(key, value string) ([]byte, error) {
+		if value == "" {
+			return nil, fmt.Errorf("kubeconfig context %s has no %s", config.CurrentContext, key)
+		}
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in kubeconfig: %w", key, err)
+		}
+		return data, nil
+	}
+	ca, err := decode("certificate-authority-data", caData)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := decode("client-certificate-data", certData)
+	if err != nil {
+		return nil, err
+	}
+	key, err := decode("client-key-data", keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid certificate authority in kubeconfig")
+	}
+	clientCert, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate in kubeconfig: %w", err)
+	}
+
+	return &APIEndpoint{
+		Server: server,
+		TLS: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientCert},
+		},
+	}, nil
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package kind
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts cmd in a new process group
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcessGroup sends SIGTERM to the process group of cmd and
+// SIGKILL to whatever is left of it after abortGrace
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	pgid := -cmd.Process.Pid
+	if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+		return err
+	}
+	time.AfterFunc(abortGrace, func() {
+		syscall.Kill(pgid, syscall.SIGKILL)
+	})
+	return nil
+}
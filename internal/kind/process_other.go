@@ -0,0 +1,16 @@
+//go:build !linux
+
+package kind
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is only supported on Linux
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup kills cmd alone; process groups are only used on Linux
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(os.Kill)
+}
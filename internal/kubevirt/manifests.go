@@ -0,0 +1,75 @@
+package kubevirt
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Version is the KubeVirt release installed into clusters
+const Version = "v1.4.0"
+
+// CacheDir holds manifests placed on the host by hand, one directory per
+// release. They take precedence over the bundled ones.
+const CacheDir = "/var/lib/host-manager/kubevirt"
+
+// Manifest file names, as published with each KubeVirt release
+const (
+	OperatorManifest = "kubevirt-operator.yaml"
+	CRManifest       = "kubevirt-cr.yaml"
+)
+
+// ReleaseURL is where the manifests of Version are published.
+// cmd/bundle-manifests downloads the operator manifest from there into
+// manifests/, next to the CR, so that it is compiled into the binary.
+const ReleaseURL = "https://github.com/kubevirt/kubevirt/releases/download/" + Version + "/"
+
+// BundleDir is the directory of this package holding the bundled manifests
+const BundleDir = "manifests"
+
+//go:embed manifests
+var bundled embed.FS
+
+// bundle and cacheDir are where manifests are looked up; tests replace them
+var (
+	bundle   fs.FS = bundled
+	cacheDir       = filepath.Join(CacheDir, Version)
+)
+
+// Manifests returns the operator and KubeVirt CR manifests of Version, each
+// read from the host cache or else the copy bundled in the binary. Nothing
+// is downloaded.
+func Manifests() (operator, cr []byte, err error) {
+	if operator, err = load(OperatorManifest); err != nil {
+		return nil, nil, err
+	}
+	if cr, err = load(CRManifest); err != nil {
+		return nil, nil, err
+	}
+	return operator, cr, nil
+}
+
+// load reads a manifest from the cache or the bundle
+func load(name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(cacheDir, name))
+	if err == nil {
+		return data, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read KubeVirt manifest: %w", err)
+	}
+
+	data, err = fs.ReadFile(bundle, path.Join(BundleDir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("KubeVirt %s manifest %s is not bundled in this binary (build it after running cmd/bundle-manifests) nor in %s", Version, name, cacheDir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundled KubeVirt manifest: %w", err)
+	}
+	return data, nil
+}
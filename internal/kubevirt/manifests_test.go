@@ -0,0 +1,71 @@
+package kubevirt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// withLookup points the manifest lookup at cache and b for one test
+func withLookup(t *testing.T, cache string, b fstest.MapFS) {
+	oldBundle, oldCache := bundle, cacheDir
+	t.Cleanup(func() { bundle, cacheDir = oldBundle, oldCache })
+	bundle, cacheDir = b, cache
+}
+
+func TestBundledCR(t *testing.T) {
+	data, err := bundled.ReadFile(BundleDir + "/" + CRManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "kind: KubeVirt") {
+		t.Errorf("bundled CR is not a KubeVirt resource:\n%s", data)
+	}
+}
+
+func TestManifestsWithEmptyCache(t *testing.T) {
+	withLookup(t, t.TempDir(), fstest.MapFS{
+		"manifests/" + OperatorManifest: {Data: []byte("operator")},
+		"manifests/" + CRManifest:       {Data: []byte("cr")},
+	})
+
+	operator, cr, err := Manifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(operator) != "operator" || string(cr) != "cr" {
+		t.Errorf("got operator %q and CR %q from the bundle", operator, cr)
+	}
+}
+
+func TestManifestsPreferCache(t *testing.T) {
+	cache := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cache, OperatorManifest), []byte("cached"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	withLookup(t, cache, fstest.MapFS{
+		"manifests/" + OperatorManifest: {Data: []byte("operator")},
+		"manifests/" + CRManifest:       {Data: []byte("cr")},
+	})
+
+	operator, cr, err := Manifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(operator) != "cached" || string(cr) != "cr" {
+		t.Errorf("got operator %q and CR %q, want the cached operator", operator, cr)
+	}
+}
+
+func TestManifestsMissing(t *testing.T) {
+	withLookup(t, t.TempDir(), fstest.MapFS{
+		"manifests/" + CRManifest: {Data: []byte("cr")},
+	})
+
+	_, _, err := Manifests()
+	if err == nil || !strings.Contains(err.Error(), "bundle-manifests") {
+		t.Errorf("Manifests() = %v, want an error pointing at cmd/bundle-manifests", err)
+	}
+}
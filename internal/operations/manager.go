@@ -0,0 +1,235 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kylape/host-manager/internal/events"
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// maxFinished is the number of completed operations kept for inspection
+const maxFinished = 100
+
+// Func performs the work of an operation, writing progress output to out
+type Func func(out io.Writer) error
+
+// Manager runs cluster operations in the background and tracks their progress.
+// Operation output and phase changes are also published to the event broker.
+type Manager struct {
+	mu         sync.RWMutex
+	operations map[string]*operation
+	broker     *events.Broker
+	running    sync.WaitGroup
+}
+
+// operation holds the mutable state of a single tracked operation
+type operation struct {
+	mu     sync.Mutex
+	info   state.Operation
+	output bytes.Buffer
+}
+
+// NewManager creates a new operation manager
+func NewManager(broker *events.Broker) *Manager {
+	return &Manager{
+		operations: make(map[string]*operation),
+		broker:     broker,
+	}
+}
+
+// Start launches fn in the background and returns the new operation. Only one
+// operation may run against a cluster at a time.
+func (m *Manager) Start(opType, cluster string, fn Func) (*state.Operation, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	for _, op := range m.operations {
+		if info := op.snapshot(); info.Cluster == cluster && !info.Done() {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("operation %s (%s) is already in progress for cluster %s", info.ID, info.Type, cluster)
+		}
+	}
+
+	now := time.Now()
+	op := &operation{
+		info: state.Operation{
+			ID:        id,
+			Type:      opType,
+			Cluster:   cluster,
+			Phase:     state.OperationPending,
+			StartTime: &now,
+		},
+	}
+	m.operations[id] = op
+	m.pruneLocked()
+	m.running.Add(1)
+	m.mu.Unlock()
+
+	// Publish the start before returning so that subscribers connecting after
+	// the caller learns the operation ID see this operation's history
+	m.broker.Publish(state.ClusterEvent{
+		Type:      state.EventStarted,
+		Cluster:   cluster,
+		Action:    opType,
+		Operation: id,
+	})
+
+	go m.run(op, fn)
+
+	info := op.snapshot()
+	return &info, nil
+}
+
+// Get returns the operation with the given ID
+func (m *Manager) Get(id string) (*state.Operation, bool) {
+	m.mu.RLock()
+	op, exists := m.operations[id]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, false
+	}
+
+	info := op.snapshot()
+	return &info, true
+}
+
+// List returns all tracked operations ordered by start time
+func (m *Manager) List() []state.Operation {
+	m.mu.RLock()
+	ops := make([]state.Operation, 0, len(m.operations))
+	for _, op := range m.operations {
+		info := op.snapshot()
+		info.Output = ""
+		ops = append(ops, info)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].StartTime.Before(*ops[j].StartTime)
+	})
+	return ops
+}
+
+// Active returns the operations that have not finished yet
+func (m *Manager) Active() []state.Operation {
+	var active []state.Operation
+	for _, op := range m.List() {
+		if !op.Done() {
+			active = append(active, op)
+		}
+	}
+	return active
+}
+
+// Wait blocks until all running operations finish or ctx is done
+func (m *Manager) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pruneLocked drops the oldest finished operations beyond maxFinished.
+// The caller must hold m.mu.
+func (m *Manager) pruneLocked() {
+	var finished []state.Operation
+	for _, op := range m.operations {
+		if info := op.snapshot(); info.Done() {
+			finished = append(finished, info)
+		}
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].EndTime.Before(*finished[j].EndTime)
+	})
+	for _, info := range finished[:len(finished)-maxFinished] {
+		delete(m.operations, info.ID)
+	}
+}
+
+// run executes fn, records the outcome and publishes the completion event.
+// The event history of a cluster is dropped once it has been deleted.
+func (m *Manager) run(op *operation, fn Func) {
+	op.mu.Lock()
+	op.info.Phase = state.OperationRunning
+	op.mu.Unlock()
+
+	lines := m.broker.NewLineWriter(op.info.Cluster, op.info.Type, op.info.ID)
+	err := fn(io.MultiWriter(op, lines))
+	lines.Flush()
+
+	defer m.running.Done()
+
+	op.mu.Lock()
+	now := time.Now()
+	op.info.EndTime = &now
+	if err != nil {
+		op.info.Phase = state.OperationFailed
+		op.info.Error = err.Error()
+	} else {
+		op.info.Phase = state.OperationSucceeded
+	}
+	info := op.info
+	op.mu.Unlock()
+
+	m.broker.Publish(state.ClusterEvent{
+		Type:      state.EventCompleted,
+		Cluster:   info.Cluster,
+		Action:    info.Type,
+		Operation: info.ID,
+		Phase:     info.Phase,
+		Error:     info.Error,
+	})
+
+	// Keep no history for every cluster name ever deleted
+	if info.Type == state.OperationDeleteCluster && info.Phase == state.OperationSucceeded {
+		m.broker.Forget(info.Cluster)
+	}
+}
+
+// Write appends command output to the operation
+func (op *operation) Write(p []byte) (int, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.output.Write(p)
+}
+
+// snapshot returns a copy of the operation's current state
+func (op *operation) snapshot() state.Operation {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	info := op.info
+	info.Output = op.output.String()
+	return info
+}
+
+// newID generates a random operation identifier
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate operation ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
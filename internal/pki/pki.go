@@ -0,0 +1,254 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 2 * 365 * 24 * time.Hour
+
+	// renewBefore is how close to expiry a bootstrap server certificate is reissued
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// Bundle holds the paths of the files created by Bootstrap
+type Bundle struct {
+	CACert     string
+	CAKey      string
+	ServerCert string
+	ServerKey  string
+}
+
+// BundleIn returns the bootstrap file locations inside dir
+func BundleIn(dir string) *Bundle {
+	return &Bundle{
+		CACert:     filepath.Join(dir, "host-manager-ca.crt"),
+		CAKey:      filepath.Join(dir, "host-manager-ca.key"),
+		ServerCert: filepath.Join(dir, "host-manager-server.crt"),
+		ServerKey:  filepath.Join(dir, "host-manager-server.key"),
+	}
+}
+
+// Bootstrap ensures a self-signed host CA and a server certificate signed by
+// it exist in dir. The CA is generated on first run and reused afterwards;
+// the server certificate is reissued when it is close to expiry or does not
+// cover all of hosts.
+func Bootstrap(dir string, hosts []string) (*Bundle, error) {
+	b := BundleIn(dir)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	caCert, caKey, err := loadKeyPair(b.CACert, b.CAKey)
+	if os.IsNotExist(err) {
+		caCert, caKey, err = createCA(b.CACert, b.CAKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up host CA: %w", err)
+	}
+
+	serverCert, _, err := loadKeyPair(b.ServerCert, b.ServerKey)
+	if err == nil && !needsReissue(serverCert, caCert, hosts) {
+		return b, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "host-manager"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	if err := issue(template, caCert, caKey, certValidity, b.ServerCert, b.ServerKey); err != nil {
+		return nil, fmt.Errorf("failed to issue server certificate: %w", err)
+	}
+
+	return b, nil
+}
+
+// IssueClientCert signs a client certificate for name with the bootstrap CA
+// in dir and writes it to certPath and keyPath
+func IssueClientCert(dir, name, certPath, keyPath string) error {
+	b := BundleIn(dir)
+	caCert, caKey, err := loadKeyPair(b.CACert, b.CAKey)
+	if err != nil {
+		return fmt.Errorf("failed to load host CA (run with --tls-bootstrap first): %w", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	return issue(template, caCert, caKey, certValidity, certPath, keyPath)
+}
+
+// ServerTLSConfig builds the TLS configuration for serving with the given
+// certificate. When clientCAFile is set, clients must present a certificate
+// signed by that CA.
+func ServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if clientCAFile != "" {
+		pool, err := LoadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// LoadCertPool returns a pool containing only the certificates in path
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// createCA generates a new self-signed CA and writes it to disk
+func createCA(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	hostname, _ := os.Hostname()
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "host-manager CA " + hostname},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	if err := issue(template, nil, nil, caValidity, certPath, keyPath); err != nil {
+		return nil, nil, err
+	}
+	return loadKeyPair(certPath, keyPath)
+}
+
+// issue creates a key and certificate from template, signed by parent (or
+// self-signed when parent is nil), and writes both as PEM files
+func issue(template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, validity time.Duration, certPath, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(validity)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key: %w", err)
+	}
+
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", keyPath, err)
+	}
+	if err := ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", certPath, err)
+	}
+
+	return nil
+}
+
+// loadKeyPair reads a PEM certificate and EC private key. A missing file is
+// reported with an error satisfying os.IsNotExist.
+func loadKeyPair(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("no certificate found in %s", certPath)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", certPath, err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("no private key found in %s", keyPath)
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", keyPath, err)
+	}
+
+	return cert, key, nil
+}
+
+// needsReissue reports whether a server certificate must be replaced
+func needsReissue(cert, ca *x509.Certificate, hosts []string) bool {
+	if time.Until(cert.NotAfter) < renewBefore {
+		return true
+	}
+	if cert.CheckSignatureFrom(ca) != nil {
+		return true
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kylape/host-manager/internal/addons"
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// addonTimeout bounds each readiness wait of an addon
+const addonTimeout = 5 * time.Minute
+
+// loadAddons resolves the addons recorded for a cluster and loads their
+// manifests, so a missing manifest fails the create before kind runs
+func loadAddons(statuses []state.AddonStatus) ([]addons.Install, [][]byte, error) {
+	var requests []state.AddonRequest
+	for _, status := range statuses {
+		requests = append(requests, state.AddonRequest{Name: status.Name, Params: status.Params})
+	}
+
+	installs, err := addons.Resolve(requests)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifests := make([][]byte, 0, len(installs))
+	for _, install := range installs {
+		manifest, err := install.Manifest()
+		if err != nil {
+			return nil, nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return installs, manifests, nil
+}
+
+// installAddons applies each addon's manifests in order and waits for it to
+// become ready, recording the progress of each addon in state. The first
+// failure stops the installation.
+func (s *Server) installAddons(name string, installs []addons.Install, manifests [][]byte, out io.Writer) error {
+	for i, install := range installs {
+		s.setStatusReason(name, "installing addon "+install.Name)
+		s.setAddonStatus(name, install.Name, state.AddonInstalling, "")
+		fmt.Fprintf(out, "Installing addon %s %s\n", install.Name, install.Version)
+
+		if err := s.installAddon(name, install, manifests[i], out); err != nil {
+			s.setAddonStatus(name, install.Name, state.AddonFailed, failureReason(err))
+			return fmt.Errorf("addon %s failed: %w", install.Name, err)
+		}
+		s.setAddonStatus(name, install.Name, state.AddonReady, "")
+	}
+	return nil
+}
+
+// installAddon applies one addon and waits for its readiness conditions
+func (s *Server) installAddon(name string, install addons.Install, manifest []byte, out io.Writer) error {
+	if err := s.kindClient.Kubectl(name, bytes.NewReader(manifest), out, "apply", "-f", "-"); err != nil {
+		return err
+	}
+
+	timeout := fmt.Sprintf("%ds", int(addonTimeout.Seconds()))
+	for _, wait := range install.Waits {
+		args := []string{"wait", wait.Resource, "--for", "condition=" + wait.Condition, "--timeout", timeout}
+		if wait.Namespace != "" {
+			args = append(args, "-n", wait.Namespace)
+		}
+		if err := s.kindClient.Kubectl(name, nil, out, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAddonStatus records the installation state of one addon of a cluster
+func (s *Server) setAddonStatus(name, addon, status, message string) {
+	err := s.stateManager.ModifyCluster(name, func(info *state.ClusterInfo) error {
+		for i := range info.Addons {
+			if info.Addons[i].Name == addon {
+				info.Addons[i].Status = status
+				info.Addons[i].Message = message
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("Failed to update addon status", "cluster", name, "addon", addon, "error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/kylape/host-manager/internal/auth"
+)
+
+// publicRoutes can be called without authentication
+var publicRoutes = map[string]bool{
+	"/health": true,
+}
+
+// adminRoutes require the admin role regardless of method
+var adminRoutes = map[string]bool{
+	"/registry/start": true,
+	"/host/reconcile": true,
+}
+
+// EnableAuth requires bearer-token authentication on all non-public routes,
+// with tokens looked up in store. It must be called before Start.
+func (s *Server) EnableAuth(store *auth.TokenStore) {
+	s.tokens = store
+}
+
+// authMiddleware authenticates the caller and checks their role against the
+// role required by the matched route
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, overUnix := r.Context().Value(peerKey{}).(*peerConn)
+		if (!overUnix && s.tokens == nil) || publicRoutes[routeTemplate(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity, ok := s.authenticate(r)
+		if !ok && overUnix {
+			http.Error(w, "Peer is not permitted to use this socket", http.StatusForbidden)
+			return
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="host-manager"`)
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		need := requiredRole(r)
+		if !auth.RoleAllows(identity.Role, need) {
+			http.Error(w, "Role "+identity.Role+" is not allowed to perform this operation (requires "+need+")", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// authenticate resolves the caller's identity. Requests on the Unix socket
+// are identified by their peer credentials, all others by their bearer token.
+func (s *Server) authenticate(r *http.Request) (*auth.Identity, bool) {
+	if conn, ok := r.Context().Value(peerKey{}).(*peerConn); ok {
+		if conn.err != nil || s.peers == nil {
+			return nil, false
+		}
+		return s.peers.Identity(*conn.peer)
+	}
+
+	if s.tokens == nil {
+		return nil, false
+	}
+	return s.tokens.Authenticate(bearerToken(r))
+}
+
+// requiredRole returns the minimum role needed for a request
+func requiredRole(r *http.Request) string {
+	if adminRoutes[routeTemplate(r)] {
+		return auth.RoleAdmin
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return auth.RoleViewer
+	}
+	return auth.RoleDeveloper
+}
+
+// callerIsAdmin reports whether the caller holds the admin role. Without
+// authentication every caller has full access.
+func callerIsAdmin(r *http.Request) bool {
+	identity, ok := auth.FromContext(r.Context())
+	return !ok || auth.RoleAllows(identity.Role, auth.RoleAdmin)
+}
+
+// routeTemplate returns the path template of the matched route
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return r.URL.Path
+}
+
+// bearerToken extracts the token from the Authorization header
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
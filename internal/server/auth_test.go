@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylape/host-manager/internal/auth"
+)
+
+// testRouter serves a few representative routes behind the auth and CORS
+// middleware of s
+func testRouter(s *Server) *mux.Router {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if identity, found := auth.FromContext(r.Context()); found {
+			w.Header().Set("X-Caller", identity.Name)
+		}
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/health", ok).Methods("GET")
+	router.HandleFunc("/clusters", ok).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/clusters/{name}", ok).Methods("DELETE")
+	router.HandleFunc("/registry/start", ok).Methods("POST")
+	router.Use(s.corsMiddleware)
+	router.Use(s.authMiddleware)
+	return router
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tokens, err := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := map[string]string{}
+	for _, role := range []string{auth.RoleViewer, auth.RoleDeveloper, auth.RoleAdmin} {
+		if token[role], err = tokens.Add(role, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{tokens: tokens}
+	router := testRouter(s)
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/health", "", http.StatusOK},
+		{"GET", "/clusters", "", http.StatusUnauthorized},
+		{"GET", "/clusters", "hm_wrong", http.StatusUnauthorized},
+		{"GET", "/clusters", token[auth.RoleViewer], http.StatusOK},
+		{"POST", "/clusters", token[auth.RoleViewer], http.StatusForbidden},
+		{"POST", "/clusters", token[auth.RoleDeveloper], http.StatusOK},
+		{"DELETE", "/clusters/dev", token[auth.RoleDeveloper], http.StatusOK},
+		{"POST", "/registry/start", token[auth.RoleDeveloper], http.StatusForbidden},
+		{"POST", "/registry/start", token[auth.RoleAdmin], http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %q: status %d, want %d", tt.method, tt.path, tt.token, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	s := &Server{}
+	s.SetCORSOrigins([]string{"https://dash.example.com"})
+	router := testRouter(s)
+
+	tests := []struct {
+		method, origin string
+		want           int
+		allowOrigin    string
+	}{
+		{"GET", "", http.StatusOK, ""},
+		{"POST", "", http.StatusOK, ""},
+		{"GET", "https://evil.example.com", http.StatusOK, ""},
+		{"POST", "https://evil.example.com", http.StatusForbidden, ""},
+		{"OPTIONS", "https://evil.example.com", http.StatusOK, ""},
+		{"POST", "https://dash.example.com", http.StatusOK, "https://dash.example.com"},
+		{"OPTIONS", "https://dash.example.com", http.StatusOK, "https://dash.example.com"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/clusters", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s from %q: status %d, want %d", tt.method, tt.origin, rec.Code, tt.want)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+			t.Errorf("%s from %q: Access-Control-Allow-Origin %q, want %q", tt.method, tt.origin, got, tt.allowOrigin)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kylape/host-manager/internal/addons"
+	"github.com/kylape/host-manager/internal/host"
+	"github.com/kylape/host-manager/internal/kind"
+	"github.com/kylape/host-manager/internal/kubevirt"
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// failureLogDir is where node logs of failed clusters kept for debugging
+// are exported
+const failureLogDir = "/var/log/host-manager"
+
+// createCluster runs a create operation for a cluster recorded as pending.
+// KubeVirt and the requested addons are installed before the cluster is
+// marked ready. A create either completes with the cluster ready or is
+// undone: on failure the partial cluster is deleted and its record removed,
+// unless the request asked to keep it, in which case its node containers
+// are retained, their logs exported and the cluster recorded as failed.
+func (s *Server) createCluster(name string, keepOnFailure bool, out io.Writer) error {
+	var info state.ClusterInfo
+	err := s.stateManager.ModifyCluster(name, func(recorded *state.ClusterInfo) error {
+		info = *recorded
+		return recorded.Transition(name, state.StatusCreating, "")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update cluster state: %w", err)
+	}
+
+	// Everything that can fail before kind runs is checked first, and a
+	// cluster this operation did not create is never rolled back
+	exists, err := s.kindClient.ClusterExists(name)
+	if err == nil && exists {
+		err = fmt.Errorf("cluster %s already exists in kind; adopt it instead", name)
+	}
+	var operator, cr []byte
+	if err == nil && info.KubeVirt {
+		operator, cr, err = kubevirt.Manifests()
+	}
+	var installs []addons.Install
+	var manifests [][]byte
+	if err == nil && len(info.Addons) > 0 {
+		installs, manifests, err = loadAddons(info.Addons)
+	}
+	if err == nil {
+		info, err = s.createClusterData(name)
+	}
+	if err != nil {
+		if err := s.stateManager.RemoveCluster(name); err != nil {
+			s.logger.Error("Failed to remove cluster from state", "cluster", name, "error", err)
+		}
+		return err
+	}
+
+	opts := kind.CreateOptions{
+		Registry:  true,
+		Retain:    keepOnFailure,
+		NodeImage: info.NodeImage,
+		Nodes:     kindNodes(info.Topology),
+	}
+	kindPorts(&opts, info.Ports)
+	for _, mount := range info.Mounts {
+		opts.Mounts = append(opts.Mounts, kind.Mount{HostPath: mount.HostPath, ContainerPath: mount.ContainerPath})
+	}
+	if info.KubeVirt {
+		opts.Mounts = append(opts.Mounts, kind.Mount{HostPath: host.KVMDevice, ContainerPath: host.KVMDevice})
+	}
+
+	createErr := s.kindClient.CreateCluster(name, opts, out)
+	if createErr == nil && info.KubeVirt {
+		createErr = s.installKubeVirt(name, operator, cr, out)
+	}
+	if createErr == nil && len(installs) > 0 {
+		createErr = s.installAddons(name, installs, manifests, out)
+	}
+	if createErr == nil {
+		if err := s.stateManager.TransitionCluster(name, state.StatusReady, ""); err != nil {
+			return fmt.Errorf("failed to update cluster state: %w", err)
+		}
+		return nil
+	}
+
+	if s.aborted() {
+		fmt.Fprintf(out, "Create of cluster %s interrupted by shutdown\n", name)
+	} else if keepOnFailure {
+		s.keepFailedCluster(name, createErr, out)
+	} else {
+		s.rollbackCluster(name, info, createErr, out)
+	}
+	return createErr
+}
+
+// createClusterData creates the data directory of a cluster and records it
+// with the host path of each mount, returning the updated record
+func (s *Server) createClusterData(name string) (state.ClusterInfo, error) {
+	hostState, err := s.stateManager.Load()
+	if err != nil {
+		return state.ClusterInfo{}, fmt.Errorf("failed to load host state: %w", err)
+	}
+	info := hostState.Clusters[name]
+
+	var subdirs []string
+	for _, mount := range info.Mounts {
+		subdirs = append(subdirs, mount.Name)
+	}
+	dir, subvolume, err := host.CreateClusterDir(name, subdirs, hostState.StorageType == "instance-store", info.DataDir)
+	if err != nil {
+		return state.ClusterInfo{}, err
+	}
+
+	err = s.stateManager.ModifyCluster(name, func(recorded *state.ClusterInfo) error {
+		recorded.DataDir = dir
+		recorded.DataSubvolume = subvolume
+		for i := range recorded.Mounts {
+			recorded.Mounts[i].HostPath = filepath.Join(dir, recorded.Mounts[i].Name)
+		}
+		info = *recorded
+		return nil
+	})
+	if err != nil {
+		host.RemoveClusterDir(dir, subvolume)
+		return state.ClusterInfo{}, fmt.Errorf("failed to update cluster state: %w", err)
+	}
+	return info, nil
+}
+
+// releaseClusterData removes or retains the data directory of a deleted
+// cluster according to policy. Failures are reported but do not fail the
+// delete, since the cluster itself is already gone.
+func (s *Server) releaseClusterData(name string, info state.ClusterInfo, policy string, out io.Writer) {
+	if info.DataDir == "" {
+		return
+	}
+
+	if policy == state.DataRetain {
+		retained, err := host.RetainClusterDir(name, info.DataDir)
+		if err != nil {
+			s.logger.Warn("Failed to retain cluster data", "cluster", name, "error", err)
+			fmt.Fprintf(out, "Warning: %v\n", err)
+			return
+		}
+		fmt.Fprintf(out, "Cluster data retained in %s\n", retained)
+		return
+	}
+
+	if err := host.RemoveClusterDir(info.DataDir, info.DataSubvolume); err != nil {
+		s.logger.Warn("Failed to remove cluster data", "cluster", name, "error", err)
+		fmt.Fprintf(out, "Warning: %v\n", err)
+	}
+}
+
+// kindNodes converts a normalized topology into kind node definitions
+func kindNodes(topology *state.Topology) []kind.NodeConfig {
+	if topology == nil {
+		return nil
+	}
+
+	nodes := make([]kind.NodeConfig, 0, len(topology.Nodes))
+	for _, spec := range topology.Nodes {
+		node := kind.NodeConfig{Role: spec.Role, Labels: spec.Labels}
+		for _, taint := range spec.Taints {
+			node.Taints = append(node.Taints, kind.Taint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// kindPorts adds a cluster's allocated host ports to its kind options
+func kindPorts(opts *kind.CreateOptions, ports []state.PortMapping) {
+	for _, mapping := range ports {
+		if mapping.Purpose == state.PortAPIServer {
+			opts.APIServerPort = mapping.HostPort
+			continue
+		}
+		opts.PortMappings = append(opts.PortMappings, kind.PortMapping{
+			ContainerPort: mapping.ContainerPort,
+			HostPort:      mapping.HostPort,
+		})
+	}
+}
+
+// rollbackCluster deletes whatever a failed create left behind and removes
+// the cluster from state. If the delete fails too, the cluster is recorded
+// as failed so it can be deleted later.
+func (s *Server) rollbackCluster(name string, info state.ClusterInfo, createErr error, out io.Writer) {
+	fmt.Fprintf(out, "Create failed; rolling back cluster %s\n", name)
+
+	if err := s.kindClient.DeleteCluster(name, out); err != nil {
+		s.logger.Error("Failed to roll back cluster", "cluster", name, "error", err)
+		reason := fmt.Sprintf("%s; rollback failed: %s", failureReason(createErr), failureReason(err))
+		if err := s.stateManager.TransitionCluster(name, state.StatusFailed, reason); err != nil {
+			s.logger.Error("Failed to record cluster failure", "cluster", name, "error", err)
+		}
+		return
+	}
+	s.dropAPIClient(name)
+
+	// The cluster never ran, so its data directory holds nothing worth keeping
+	s.releaseClusterData(name, info, state.DataDelete, out)
+	if err := s.stateManager.RemoveCluster(name); err != nil {
+		s.logger.Error("Failed to remove cluster from state", "cluster", name, "error", err)
+	}
+}
+
+// keepFailedCluster records a failed create, exporting the node logs so the
+// failure can be investigated
+func (s *Server) keepFailedCluster(name string, createErr error, out io.Writer) {
+	reason := failureReason(createErr)
+
+	dir := filepath.Join(failureLogDir, name+"-"+time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(failureLogDir, 0755); err != nil {
+		s.logger.Warn("Failed to create log directory", "error", err)
+		dir = ""
+	} else if err := s.kindClient.ExportLogs(name, dir, out); err != nil {
+		s.logger.Warn("Failed to export cluster logs", "cluster", name, "error", err)
+		dir = ""
+	}
+
+	err := s.stateManager.ModifyCluster(name, func(info *state.ClusterInfo) error {
+		info.FailureLogs = dir
+		return info.Transition(name, state.StatusFailed, reason)
+	})
+	if err != nil {
+		s.logger.Error("Failed to record cluster failure", "cluster", name, "error", err)
+	}
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kylape/host-manager/internal/kind"
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// probeTimeout bounds each request a health probe makes to an API server
+const probeTimeout = 5 * time.Second
+
+// StartHealthProber probes every cluster immediately and then every
+// interval until the server shuts down. Results are cached and served with
+// cluster responses.
+func (s *Server) StartHealthProber(interval time.Duration) {
+	go func() {
+		s.probeClusters()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.shutdownCh:
+				return
+			case <-ticker.C:
+				s.probeClusters()
+			}
+		}
+	}()
+}
+
+// probeClusters probes all clusters that should be running in parallel and
+// replaces the health cache. Clusters that are stopped, lost or busy with an
+// operation are not probed and have no health.
+func (s *Server) probeClusters() {
+	hostState, err := s.stateManager.Load()
+	if err != nil {
+		s.logger.Warn("Health probe failed to load state", "error", err)
+		return
+	}
+
+	nodes, nodesErr := s.kindClient.ListNodes()
+	busy := s.busyClusters()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]*state.ClusterHealth)
+	for name, info := range hostState.Clusters {
+		if busy[name] || !state.Usable(info.Status) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			health := s.probeCluster(name, nodes, nodesErr)
+			mu.Lock()
+			results[name] = health
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+
+	s.healthMu.Lock()
+	s.health = results
+	s.healthMu.Unlock()
+}
+
+// probeCluster runs the health checks of one cluster. The node-ready check
+// needs the API server and is failed without trying when it is unreachable.
+func (s *Server) probeCluster(name string, nodes map[string][]kind.Node, nodesErr error) *state.ClusterHealth {
+	health := &state.ClusterHealth{Status: state.HealthHealthy, CheckedAt: time.Now()}
+	record := func(check string, start time.Time, err error) {
+		result := state.HealthCheck{
+			Name:      check,
+			OK:        err == nil,
+			CheckedAt: start,
+			Duration:  time.Since(start).Round(time.Millisecond).String(),
+		}
+		if err != nil {
+			result.Message = err.Error()
+			health.Status = state.HealthUnhealthy
+		}
+		health.Checks = append(health.Checks, result)
+	}
+
+	start := time.Now()
+	record(state.CheckNodes, start, checkNodes(nodes, name, nodesErr))
+
+	start = time.Now()
+	client, server, err := s.apiClient(name)
+	if err == nil {
+		err = checkReadyz(client, server)
+	}
+	record(state.CheckAPIServer, start, err)
+
+	start = time.Now()
+	if err == nil {
+		err = checkNodeReady(client, server)
+	} else {
+		err = fmt.Errorf("API server unavailable")
+	}
+	record(state.CheckNodeReady, start, err)
+
+	return health
+}
+
+// checkNodes verifies that podman reports every node container running
+func checkNodes(nodes map[string][]kind.Node, cluster string, listErr error) error {
+	if listErr != nil {
+		return listErr
+	}
+	if len(nodes[cluster]) == 0 {
+		return fmt.Errorf("no node containers found")
+	}
+	if stopped := stoppedNodes(nodes, cluster); len(stopped) > 0 {
+		return fmt.Errorf("node containers not running: %s", strings.Join(stopped, ", "))
+	}
+	var paused []string
+	for _, node := range nodes[cluster] {
+		if node.Paused {
+			paused = append(paused, node.Name)
+		}
+	}
+	if len(paused) > 0 {
+		return fmt.Errorf("node containers paused: %s", strings.Join(paused, ", "))
+	}
+	return nil
+}
+
+// apiEndpoint is a cached client for the API server of one cluster
+type apiEndpoint struct {
+	client *http.Client
+	server string
+}
+
+// apiClient returns an HTTP client authenticated as the cluster admin and
+// the API server address, taken from the cluster's kubeconfig. Clients are
+// cached per cluster so probes and polls reuse their connections, until
+// dropAPIClient is called.
+func (s *Server) apiClient(name string) (*http.Client, string, error) {
+	s.apiMu.Lock()
+	cached, ok := s.apiClients[name]
+	s.apiMu.Unlock()
+	if ok {
+		return cached.client, cached.server, nil
+	}
+
+	kubeconfig, err := s.kindClient.GetKubeconfig(name)
+	if err != nil {
+		return nil, "", err
+	}
+	endpoint, err := kind.ParseKubeconfig(kubeconfig)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cached = &apiEndpoint{
+		client: &http.Client{
+			Timeout: probeTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig:     endpoint.TLS,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		server: endpoint.Server,
+	}
+
+	s.apiMu.Lock()
+	defer s.apiMu.Unlock()
+	if existing, ok := s.apiClients[name]; ok {
+		return existing.client, existing.server, nil
+	}
+	s.apiClients[name] = cached
+	return cached.client, cached.server, nil
+}
+
+// dropAPIClient closes and forgets the cached API client of a cluster whose
+// API server went away
+func (s *Server) dropAPIClient(name string) {
+	s.apiMu.Lock()
+	defer s.apiMu.Unlock()
+	if cached, ok := s.apiClients[name]; ok {
+		cached.client.CloseIdleConnections()
+		delete(s.apiClients, name)
+	}
+}
+
+// checkReadyz queries the API server's readiness endpoint
+func checkReadyz(client *http.Client, server string) error {
+	resp, err := client.Get(server + "/readyz")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("/readyz returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// nodeList is the part of a Kubernetes NodeList the node-ready check reads
+type nodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// checkNodeReady verifies that every node reports the Ready condition
+func checkNodeReady(client *http.Client, server string) error {
+	resp, err := client.Get(server + "/api/v1/nodes")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("listing nodes returned %d", resp.StatusCode)
+	}
+
+	var nodes nodeList
+	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+		return fmt.Errorf("failed to decode node list: %w", err)
+	}
+
+	var notReady []string
+	for _, node := range nodes.Items {
+		ready := false
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == "Ready" && condition.Status == "True" {
+				ready = true
+			}
+		}
+		if !ready {
+			notReady = append(notReady, node.Metadata.Name)
+		}
+	}
+	if len(notReady) > 0 {
+		return fmt.Errorf("nodes not ready: %s", strings.Join(notReady, ", "))
+	}
+	return nil
+}
+
+// cachedHealth returns the last probe result of a cluster, or nil
+func (s *Server) cachedHealth(name string) *state.ClusterHealth {
+	s.healthMu.RLock()
+	defer s.healthMu.RUnlock()
+	return s.health[name]
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// IdleOptions controls automatic pausing of unused clusters
+type IdleOptions struct {
+	Interval time.Duration // how often clusters are checked
+	Timeout  time.Duration // how long a cluster may go unused before it is paused
+
+	// APIRequests also counts requests to each cluster's API server as use,
+	// leaving out the periodic traffic the cluster generates on its own.
+	// The API server's metrics do not name the client, so requests that
+	// addons make without watching count as use too; see userRequest.
+	APIRequests bool
+}
+
+// StartIdleMonitor checks clusters for use every opts.Interval until the
+// server shuts down, pausing those unused for opts.Timeout
+func (s *Server) StartIdleMonitor(opts IdleOptions) {
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.shutdownCh:
+				return
+			case <-ticker.C:
+				s.checkIdle(opts)
+			}
+		}
+	}()
+}
+
+// checkIdle pauses ready development clusters that have not been used
+// within the idle timeout. A cluster's idle time counts from its last
+// recorded activity or status change, whichever is later.
+func (s *Server) checkIdle(opts IdleOptions) {
+	hostState, err := s.stateManager.Load()
+	if err != nil {
+		s.logger.Warn("Idle check failed to load state", "error", err)
+		return
+	}
+
+	s.idleMu.Lock()
+	for name := range s.requestCounts {
+		if hostState.Clusters[name].Status != state.StatusReady {
+			delete(s.requestCounts, name)
+		}
+	}
+	s.idleMu.Unlock()
+
+	busy := s.busyClusters()
+	for name, info := range hostState.Clusters {
+		if busy[name] || info.Status != state.StatusReady || name == "kind" || info.Type == "infrastructure" {
+			continue
+		}
+
+		if opts.APIRequests {
+			if active, err := s.apiActivity(name); err != nil {
+				s.logger.Debug("Failed to read API server request counts", "cluster", name, "error", err)
+			} else if active {
+				s.recordActivity(name)
+				continue
+			}
+		}
+
+		since := lastUse(info)
+		if since != nil && time.Since(*since) >= opts.Timeout {
+			s.pauseCluster(name, *since)
+		}
+	}
+}
+
+// lastUse returns when a cluster was last used or changed status
+func lastUse(info state.ClusterInfo) *time.Time {
+	since := info.LastTransition
+	if info.LastActivity != nil && (since == nil || info.LastActivity.After(*since)) {
+		since = info.LastActivity
+	}
+	return since
+}
+
+// pauseCluster pauses an idle cluster's node containers, unless it was used
+// or changed since idleSince. The cluster is recorded as paused first, so
+// nothing judges its frozen nodes as stopped, and returned to ready if the
+// pause fails.
+func (s *Server) pauseCluster(name string, idleSince time.Time) {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	if s.busyClusters()[name] {
+		return
+	}
+	reason := "idle since " + idleSince.UTC().Format(time.RFC3339)
+	paused := false
+	err := s.stateManager.ModifyCluster(name, func(info *state.ClusterInfo) error {
+		if info.Status != state.StatusReady {
+			return nil
+		}
+		if since := lastUse(*info); since == nil || !since.Equal(idleSince) {
+			return nil
+		}
+		paused = true
+		return info.Transition(name, state.StatusPaused, reason)
+	})
+	if err != nil {
+		if !errors.Is(err, state.ErrClusterNotFound) {
+			s.logger.Warn("Failed to record paused cluster", "cluster", name, "error", err)
+		}
+		return
+	}
+	if !paused {
+		return
+	}
+
+	if err := s.kindClient.PauseCluster(name, nil); err != nil {
+		s.logger.Warn("Failed to pause idle cluster", "cluster", name, "error", err)
+		// Leave no node frozen behind a cluster recorded as ready
+		if err := s.kindClient.UnpauseCluster(name, nil); err != nil {
+			s.logger.Warn("Failed to unpause cluster", "cluster", name, "error", err)
+		}
+		if err := s.stateManager.TransitionCluster(name, state.StatusReady, ""); err != nil {
+			s.logger.Error("Failed to record resumed cluster", "cluster", name, "error", err)
+		}
+		return
+	}
+	s.logger.Info("Paused idle cluster", "cluster", name, "idle_since", idleSince.Format(time.RFC3339))
+}
+
+// useCluster is called before a request uses a cluster: it resumes the
+// cluster if it is paused and records the activity, so that it is not
+// paused again for the idle timeout. Unknown clusters are ignored.
+//
+// Every handler that reaches a cluster's nodes or API server calls it.
+// Reading or updating the cluster's record and following its events do
+// not: they only touch host state, and a dashboard polling them would
+// otherwise keep every cluster awake.
+func (s *Server) useCluster(name string) error {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	status, err := s.clusterStatus(name)
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return nil
+	}
+
+	if status == state.StatusPaused {
+		if err := s.kindClient.UnpauseCluster(name, nil); err != nil {
+			return fmt.Errorf("failed to resume cluster %s: %w", name, err)
+		}
+		if err := s.stateManager.TransitionCluster(name, state.StatusReady, ""); err != nil {
+			return fmt.Errorf("failed to update cluster state: %w", err)
+		}
+		s.logger.Info("Resumed paused cluster", "cluster", name)
+	}
+
+	s.recordActivity(name)
+	return nil
+}
+
+// recordActivity notes that a cluster is in use
+func (s *Server) recordActivity(name string) {
+	now := time.Now()
+	err := s.stateManager.ModifyCluster(name, func(info *state.ClusterInfo) error {
+		info.LastActivity = &now
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("Failed to record cluster activity", "cluster", name, "error", err)
+	}
+}
+
+// apiActivity reports whether a cluster's API server served requests from
+// users since the last check. The first check of a cluster only records a
+// baseline.
+func (s *Server) apiActivity(name string) (bool, error) {
+	client, server, err := s.apiClient(name)
+	if err != nil {
+		return false, err
+	}
+	count, err := userRequests(client, server)
+	if err != nil {
+		return false, err
+	}
+
+	s.idleMu.Lock()
+	defer s.idleMu.Unlock()
+	previous, seen := s.requestCounts[name]
+	s.requestCounts[name] = count
+	// Any change counts; a lower count means the API server restarted
+	return seen && count != previous, nil
+}
+
+// backgroundResources are read or written periodically by the cluster's own
+// components: kubelets heartbeat through leases and nodes, the API server
+// reconciles the endpoints of the kubernetes service, controllers record
+// events, and authentication goes through token and access reviews.
+// Requests for them do not show that anyone uses the cluster.
+var backgroundResources = map[string]bool{
+	"leases":               true,
+	"events":               true,
+	"nodes":                true,
+	"endpoints":            true,
+	"endpointslices":       true,
+	"tokenreviews":         true,
+	"subjectaccessreviews": true,
+}
+
+// userRequests sums the requests an API server has served for resources,
+// reads as well as writes, leaving out the traffic the cluster generates on
+// its own: watches, which controllers hold open permanently, non-resource
+// paths such as /readyz and /metrics that probes poll, status and token
+// subresources, backgroundResources, and the cluster-wide service list the
+// API server's IP repair loop runs every few minutes. Host-manager's own
+// probes only read non-resource paths and nodes, so they are not counted.
+func userRequests(client *http.Client, server string) (float64, error) {
+	resp, err := client.Get(server + "/metrics")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("/metrics returned %d", resp.StatusCode)
+	}
+
+	var total float64
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "apiserver_request_total{") {
+			continue
+		}
+
+		end := strings.LastIndex(line, "}")
+		if end < 0 {
+			continue
+		}
+		labels := parseLabels(line[len("apiserver_request_total{"):end])
+		if !userRequest(labels) {
+			continue
+		}
+
+		fields := strings.Fields(line[end+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		total += value
+	}
+	return total, scanner.Err()
+}
+
+// userRequest reports whether requests with the labels of an
+// apiserver_request_total sample can come from someone using the cluster.
+// The sample has no label for the client, so this goes by resource and
+// verb alone: an addon that gets or lists config maps, secrets or pods
+// without watching them is taken for a user.
+func userRequest(labels map[string]string) bool {
+	resource := labels["resource"]
+	switch {
+	case labels["verb"] == "WATCH" || resource == "":
+		return false
+	case backgroundResources[resource]:
+		return false
+	case labels["subresource"] == "status" || labels["subresource"] == "token":
+		return false
+	case resource == "services" && labels["verb"] == "LIST" && labels["scope"] == "cluster":
+		return false
+	}
+	return true
+}
+
+// parseLabels parses the label set of a Prometheus text-format sample,
+// unescaping the values
+func parseLabels(s string) map[string]string {
+	labels := make(map[string]string)
+	for s != "" {
+		key, rest, ok := strings.Cut(s, `="`)
+		if !ok {
+			break
+		}
+		var value strings.Builder
+		end := -1
+		for i := 0; i < len(rest); i++ {
+			if rest[i] == '"' {
+				end = i
+				break
+			}
+			if rest[i] == '\\' && i+1 < len(rest) {
+				i++
+				if rest[i] == 'n' {
+					value.WriteByte('\n')
+					continue
+				}
+			}
+			value.WriteByte(rest[i])
+		}
+		if end < 0 {
+			break
+		}
+		labels[strings.TrimSpace(key)] = value.String()
+		s = strings.TrimPrefix(rest[end+1:], ",")
+	}
+	return labels
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// metricsSample is an excerpt of a kind cluster's /metrics running
+// cert-manager, with the help text shortened
+const metricsSample = `# HELP apiserver_request_total [STABLE] Counter of apiserver requests.
+# TYPE apiserver_request_total counter
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="",scope="",subresource="/readyz",verb="GET",version=""} 1529
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="configmaps",scope="namespace",subresource="",verb="GET",version="v1"} 212
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="configmaps",scope="namespace",subresource="",verb="WATCH",version="v1"} 18
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="endpoints",scope="namespace",subresource="",verb="GET",version="v1"} 410
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="nodes",scope="resource",subresource="status",verb="PATCH",version="v1"} 77
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="pods",scope="namespace",subresource="exec",verb="CONNECT",version="v1"} 3
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="pods",scope="namespace",subresource="log",verb="GET",version="v1"} 5
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="serviceaccounts",scope="namespace",subresource="token",verb="POST",version="v1"} 41
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="services",scope="cluster",subresource="",verb="LIST",version="v1"} 9
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="coordination.k8s.io",resource="leases",scope="namespace",subresource="",verb="PUT",version="v1"} 2250
+apiserver_request_total{code="201",component="apiserver",dry_run="",group="apps",resource="deployments",scope="namespace",subresource="",verb="POST",version="v1"} 2
+apiserver_request_total{code="201",component="apiserver",dry_run="",group="authentication.k8s.io",resource="tokenreviews",scope="cluster",subresource="",verb="POST",version="v1"} 64
+# HELP apiserver_request_duration_seconds [STABLE] Response latency distribution in seconds.
+# TYPE apiserver_request_duration_seconds histogram
+apiserver_request_duration_seconds_bucket{component="apiserver",dry_run="",group="",resource="pods",scope="namespace",subresource="",verb="LIST",version="v1",le="0.005"} 120
+`
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{`code="200",resource="pods",verb="GET"`, map[string]string{"code": "200", "resource": "pods", "verb": "GET"}},
+		{`dry_run="",group=""`, map[string]string{"dry_run": "", "group": ""}},
+		{`path="/a,b",verb="GET"`, map[string]string{"path": "/a,b", "verb": "GET"}},
+		{`msg="say \"hi\"",next="x"`, map[string]string{"msg": `say "hi"`, "next": "x"}},
+		{`dir="C:\\",next="x"`, map[string]string{"dir": `C:\`, "next": "x"}},
+		{`text="a\nb"`, map[string]string{"text": "a\nb"}},
+		{`broken="open`, map[string]string{}},
+	}
+	for _, test := range tests {
+		got := parseLabels(test.in)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("parseLabels(%s) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestUserRequest(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{"verb": "GET", "resource": "pods", "subresource": "log"}, true},
+		{map[string]string{"verb": "POST", "resource": "deployments"}, true},
+		{map[string]string{"verb": "LIST", "resource": "services", "scope": "namespace"}, true},
+		// Addons reading config maps are indistinguishable from users
+		{map[string]string{"verb": "GET", "resource": "configmaps"}, true},
+		{map[string]string{"verb": "WATCH", "resource": "pods"}, false},
+		{map[string]string{"verb": "GET", "subresource": "/readyz"}, false},
+		{map[string]string{"verb": "PUT", "resource": "leases"}, false},
+		{map[string]string{"verb": "PATCH", "resource": "nodes", "subresource": "status"}, false},
+		{map[string]string{"verb": "POST", "resource": "serviceaccounts", "subresource": "token"}, false},
+		{map[string]string{"verb": "LIST", "resource": "services", "scope": "cluster"}, false},
+	}
+	for _, test := range tests {
+		if got := userRequest(test.labels); got != test.want {
+			t.Errorf("userRequest(%v) = %v, want %v", test.labels, got, test.want)
+		}
+	}
+}
+
+func TestUserRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, metricsSample)
+	}))
+	defer server.Close()
+
+	got, err := userRequests(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// configmaps GET, pods exec and log, deployments POST
+	if want := float64(212 + 3 + 5 + 2); got != want {
+		t.Errorf("userRequests() = %v, want %v", got, want)
+	}
+}
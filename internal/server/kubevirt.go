@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kylape/host-manager/internal/kubevirt"
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// kubeVirtTimeout bounds how long a create waits for KubeVirt to become
+// available; virt-handler images are large and pulled on every node
+const kubeVirtTimeout = 15 * time.Minute
+
+// installKubeVirt applies the KubeVirt operator and CR manifests to a newly
+// created cluster and waits until KubeVirt reports Available
+func (s *Server) installKubeVirt(name string, operator, cr []byte, out io.Writer) error {
+	s.setStatusReason(name, "installing KubeVirt "+kubevirt.Version)
+	fmt.Fprintf(out, "Installing KubeVirt %s\n", kubevirt.Version)
+
+	if err := s.kindClient.Kubectl(name, bytes.NewReader(operator), out, "apply", "-f", "-"); err != nil {
+		return err
+	}
+	if err := s.kindClient.Kubectl(name, nil, out, "wait", "crd/kubevirts.kubevirt.io", "--for", "condition=Established", "--timeout", "2m"); err != nil {
+		return err
+	}
+	if err := s.kindClient.Kubectl(name, bytes.NewReader(cr), out, "apply", "-f", "-"); err != nil {
+		return err
+	}
+
+	s.setStatusReason(name, "waiting for KubeVirt to become available")
+	timeout := fmt.Sprintf("%ds", int(kubeVirtTimeout.Seconds()))
+	if err := s.kindClient.Kubectl(name, nil, out, "wait", "-n", "kubevirt", "kv/kubevirt", "--for", "condition=Available", "--timeout", timeout); err != nil {
+		return fmt.Errorf("KubeVirt did not become available: %w", err)
+	}
+	return nil
+}
+
+// setStatusReason records progress of a running operation without changing
+// the cluster's status
+func (s *Server) setStatusReason(name, reason string) {
+	err := s.stateManager.ModifyCluster(name, func(info *state.ClusterInfo) error {
+		info.StatusReason = reason
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("Failed to update cluster status reason", "cluster", name, "error", err)
+	}
+}
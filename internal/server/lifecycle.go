@@ -0,0 +1,219 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// startTimeout bounds how long a started cluster's API server may take to
+// become ready
+const startTimeout = 5 * time.Minute
+
+// handleStopCluster stops the node containers of a running cluster. Its
+// ports, data directory and node containers are kept for a later start.
+func (s *Server) handleStopCluster(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if name == "kind" {
+		http.Error(w, "Cannot stop infrastructure cluster", http.StatusForbidden)
+		return
+	}
+	if !s.checkLifecycle(w, name, "stop", state.StatusStopping) {
+		return
+	}
+	if err := s.useCluster(name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	op, err := s.operations.Start(state.OperationStopCluster, name, func(out io.Writer) error {
+		return s.stopCluster(name, out)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeOperation(w, op)
+}
+
+// handleStartCluster starts a stopped or degraded cluster and waits for its
+// API server. A paused cluster is resumed instead.
+func (s *Server) handleStartCluster(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	status, err := s.clusterStatus(name)
+	if err != nil {
+		http.Error(w, "Failed to load host state", http.StatusInternalServerError)
+		return
+	}
+	if status == "" {
+		http.Error(w, "Cluster not found", http.StatusNotFound)
+		return
+	}
+	if status != state.StatusStopped && status != state.StatusDegraded && status != state.StatusPaused {
+		writeTransitionError(w, name, status, "start")
+		return
+	}
+
+	op, err := s.operations.Start(state.OperationStartCluster, name, func(out io.Writer) error {
+		if status == state.StatusPaused {
+			fmt.Fprintf(out, "Resuming paused cluster %s\n", name)
+			return s.useCluster(name)
+		}
+		return s.startCluster(name, out)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeOperation(w, op)
+}
+
+// handleRestartCluster stops and starts a running cluster in one operation
+func (s *Server) handleRestartCluster(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if name == "kind" {
+		http.Error(w, "Cannot restart infrastructure cluster", http.StatusForbidden)
+		return
+	}
+	if !s.checkLifecycle(w, name, "restart", state.StatusStopping) {
+		return
+	}
+	if err := s.useCluster(name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	op, err := s.operations.Start(state.OperationRestartCluster, name, func(out io.Writer) error {
+		if err := s.stopCluster(name, out); err != nil {
+			return err
+		}
+		return s.startCluster(name, out)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeOperation(w, op)
+}
+
+// checkLifecycle responds with 404 or 409 and returns false unless the
+// cluster exists and may move to status to. A paused cluster is judged as
+// ready, since it is resumed before it is used.
+func (s *Server) checkLifecycle(w http.ResponseWriter, name, action, to string) bool {
+	status, err := s.clusterStatus(name)
+	if err != nil {
+		http.Error(w, "Failed to load host state", http.StatusInternalServerError)
+		return false
+	}
+	if status == "" {
+		http.Error(w, "Cluster not found", http.StatusNotFound)
+		return false
+	}
+	from := status
+	if from == state.StatusPaused {
+		from = state.StatusReady
+	}
+	if !state.CanTransition(from, to) {
+		writeTransitionError(w, name, status, action)
+		return false
+	}
+	return true
+}
+
+// stopCluster stops the node containers of a cluster, recording it as
+// stopped. A cluster that only partly stopped is marked degraded.
+func (s *Server) stopCluster(name string, out io.Writer) error {
+	if err := s.stateManager.TransitionCluster(name, state.StatusStopping, ""); err != nil {
+		return fmt.Errorf("failed to update cluster state: %w", err)
+	}
+
+	fmt.Fprintf(out, "Stopping cluster %s\n", name)
+	if err := s.kindClient.StopCluster(name, out); err != nil {
+		s.markDegraded(name, err)
+		return err
+	}
+
+	s.healthMu.Lock()
+	delete(s.health, name)
+	s.healthMu.Unlock()
+	s.dropAPIClient(name)
+
+	if err := s.stateManager.TransitionCluster(name, state.StatusStopped, ""); err != nil {
+		return fmt.Errorf("failed to update cluster state: %w", err)
+	}
+	return nil
+}
+
+// startCluster starts the node containers of a cluster and waits for its
+// API server before recording it as ready. A cluster whose nodes or API
+// server did not come up is marked degraded.
+func (s *Server) startCluster(name string, out io.Writer) error {
+	s.setStatusReason(name, "starting")
+
+	fmt.Fprintf(out, "Starting cluster %s\n", name)
+	err := s.kindClient.StartCluster(name, out)
+	if err == nil {
+		fmt.Fprintf(out, "Waiting for the API server of %s\n", name)
+		err = s.waitAPIServer(name, startTimeout)
+	}
+	if err != nil {
+		s.markDegraded(name, err)
+		return err
+	}
+
+	if err := s.stateManager.TransitionCluster(name, state.StatusReady, ""); err != nil {
+		return fmt.Errorf("failed to update cluster state: %w", err)
+	}
+	return nil
+}
+
+// markDegraded records that a stop or start left a cluster partly running
+func (s *Server) markDegraded(name string, cause error) {
+	err := s.stateManager.ModifyCluster(name, func(info *state.ClusterInfo) error {
+		// A stop cut short by shutdown is left for Shutdown to mark
+		if s.aborted() && state.Transitional(info.Status) {
+			return nil
+		}
+		if info.Status == state.StatusDegraded {
+			info.StatusReason = failureReason(cause)
+			return nil
+		}
+		return info.Transition(name, state.StatusDegraded, failureReason(cause))
+	})
+	if err != nil {
+		s.logger.Error("Failed to record cluster failure", "cluster", name, "error", err)
+	}
+}
+
+// waitAPIServer polls the API server of a cluster until /readyz succeeds,
+// timeout passes or shutdown aborts running commands
+func (s *Server) waitAPIServer(name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		client, server, err := s.apiClient(name)
+		if err == nil {
+			err = checkReadyz(client, server)
+		}
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("API server of %s not ready after %s: %w", name, timeout, err)
+		}
+		select {
+		case <-s.kindClient.Aborted():
+			return fmt.Errorf("API server of %s not ready: wait aborted by shutdown", name)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
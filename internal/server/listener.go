@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/kylape/host-manager/internal/auth"
+)
+
+// peerKey is the context key for the peer of a Unix socket connection
+type peerKey struct{}
+
+// peerConn records the peer credentials looked up for a Unix socket connection
+type peerConn struct {
+	peer *auth.Peer
+	err  error
+}
+
+// Start starts the HTTP server on a TCP address
+func (s *Server) Start(addr string) error {
+	s.logger.Info("Starting HTTP server", "address", addr)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return s.Serve(listener)
+}
+
+// StartTLS starts the HTTPS server on a TCP address with the given TLS configuration
+func (s *Server) StartTLS(addr string, tlsConfig *tls.Config) error {
+	s.logger.Info("Starting HTTPS server", "address", addr, "mtls", tlsConfig.ClientCAs != nil)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return s.Serve(tls.NewListener(listener, tlsConfig))
+}
+
+// StartUnix starts the HTTP server on a Unix domain socket. Callers are
+// authorized by their peer credentials according to the policy set with
+// EnablePeerAuth; without a policy every request on the socket is rejected.
+func (s *Server) StartUnix(path string) error {
+	s.logger.Info("Starting HTTP server", "socket", path)
+
+	// Remove a stale socket left behind by a previous run
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	// Access is decided per request from the peer credentials
+	if err := os.Chmod(path, 0666); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener. It may be called for several
+// listeners concurrently.
+func (s *Server) Serve(listener net.Listener) error {
+	return s.httpServer.Serve(listener)
+}
+
+// EnablePeerAuth sets the policy mapping Unix socket peers to roles. It must
+// be called before StartUnix.
+func (s *Server) EnablePeerAuth(policy *auth.PeerPolicy) {
+	s.peers = policy
+}
+
+// connContext attaches the peer credentials of Unix socket connections to
+// the context of every request served on them
+func (s *Server) connContext(ctx context.Context, conn net.Conn) context.Context {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+
+	peer, err := peerCredentials(unixConn)
+	return context.WithValue(ctx, peerKey{}, &peerConn{peer: peer, err: err})
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/kylape/host-manager/internal/host"
+)
+
+// durationBuckets are histogram buckets in seconds suited to both HTTP
+// requests and long-running kind commands
+var durationBuckets = []float64{0.005, 0.025, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// serverMetrics holds the metrics updated while serving requests and running commands
+type serverMetrics struct {
+	handler http.Handler
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	commandDuration  *prometheus.HistogramVec
+	commandFailures  *prometheus.CounterVec
+	commandsExecuted *prometheus.CounterVec
+
+	// Sampled by the reconciler rather than on every scrape
+	registryUp       prometheus.Gauge
+	storageSize      *prometheus.GaugeVec
+	storageAvailable *prometheus.GaugeVec
+}
+
+// setupMetrics creates the metrics registry and hooks it into the kind client
+func (s *Server) setupMetrics() {
+	m := &serverMetrics{
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "host_manager_http_requests_total",
+			Help: "HTTP requests handled, by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "host_manager_http_request_duration_seconds",
+			Help:    "HTTP request latency in seconds, by route and method.",
+			Buckets: durationBuckets,
+		}, []string{"route", "method"}),
+		commandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "host_manager_command_duration_seconds",
+			Help:    "Duration of kind and podman command executions in seconds.",
+			Buckets: durationBuckets,
+		}, []string{"command", "subcommand"}),
+		commandFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "host_manager_command_failures_total",
+			Help: "kind and podman command executions that failed.",
+		}, []string{"command", "subcommand"}),
+		commandsExecuted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "host_manager_commands_total",
+			Help: "kind and podman command executions.",
+		}, []string{"command", "subcommand"}),
+		registryUp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "host_manager_registry_up",
+			Help: "Whether the shared kind registry container is running.",
+		}),
+		storageSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "host_manager_storage_size_bytes",
+			Help: "Size of the filesystem on the host storage device.",
+		}, []string{"device", "mountpoint"}),
+		storageAvailable: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "host_manager_storage_available_bytes",
+			Help: "Space available on the filesystem on the host storage device.",
+		}, []string{"device", "mountpoint"}),
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		m.httpRequests,
+		m.httpDuration,
+		m.commandsExecuted,
+		m.commandFailures,
+		m.commandDuration,
+		m.registryUp,
+		m.storageSize,
+		m.storageAvailable,
+		clusterCollector{s},
+	)
+	m.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+
+	s.kindClient.SetObserver(func(command, subcommand string, duration time.Duration, err error) {
+		m.commandsExecuted.WithLabelValues(command, subcommand).Inc()
+		m.commandDuration.WithLabelValues(command, subcommand).Observe(duration.Seconds())
+		if err != nil {
+			m.commandFailures.WithLabelValues(command, subcommand).Inc()
+		}
+	})
+
+	s.metrics = m
+}
+
+// metricsMiddleware records request counts and latencies per route
+func (s *Server) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: 200}
+
+		next.ServeHTTP(wrapped, r)
+
+		route := routeTemplate(r)
+		s.metrics.httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(wrapped.statusCode)).Inc()
+		s.metrics.httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// sampleHostMetrics records whether the registry is running and how full the
+// host storage is. The reconciler calls it so that scrapes run no commands.
+func (s *Server) sampleHostMetrics() {
+	up := 0.0
+	if s.kindClient.RegistryRunning() {
+		up = 1
+	}
+	s.metrics.registryUp.Set(up)
+
+	hostState, err := s.stateManager.Load()
+	if err != nil {
+		return
+	}
+	s.metrics.storageSize.Reset()
+	s.metrics.storageAvailable.Reset()
+	mountpoint, total, free, err := host.DiskUsage(hostState.StorageDevice)
+	if err != nil {
+		return
+	}
+	s.metrics.storageSize.WithLabelValues(hostState.StorageDevice, mountpoint).Set(float64(total))
+	s.metrics.storageAvailable.WithLabelValues(hostState.StorageDevice, mountpoint).Set(float64(free))
+}
+
+// clusterDesc describes the cluster count gauge
+var clusterDesc = prometheus.NewDesc("host_manager_clusters",
+	"Clusters recorded in host state, by status and type.", []string{"status", "type"}, nil)
+
+// clusterCollector reports cluster counts by status and type, loading state
+// once per scrape
+type clusterCollector struct {
+	s *Server
+}
+
+// Describe implements prometheus.Collector
+func (c clusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterDesc
+}
+
+// Collect implements prometheus.Collector
+func (c clusterCollector) Collect(ch chan<- prometheus.Metric) {
+	hostState, err := c.s.stateManager.Load()
+	if err != nil {
+		return
+	}
+
+	type key struct{ status, clusterType string }
+	counts := make(map[key]int)
+	for _, info := range hostState.Clusters {
+		counts[key{info.Status, info.Type}]++
+	}
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(clusterDesc, prometheus.GaugeValue, float64(count), k.status, k.clusterType)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build linux
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/kylape/host-manager/internal/auth"
+)
+
+// peerCredentials returns the credentials of the process connected to a Unix socket
+func peerCredentials(conn *net.UnixConn) (*auth.Peer, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	if credErr != nil {
+		return nil, fmt.Errorf("SO_PEERCRED failed: %w", credErr)
+	}
+
+	return &auth.Peer{PID: cred.Pid, UID: cred.Uid, GID: cred.Gid}, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/kylape/host-manager/internal/auth"
+)
+
+// peerCredentials is only supported on Linux
+func peerCredentials(conn *net.UnixConn) (*auth.Peer, error) {
+	return nil, fmt.Errorf("peer credentials are not supported on this platform")
+}
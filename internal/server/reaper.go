@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// StartReaper checks cluster expiry immediately and then every interval
+// until the server shuts down. Clusters are warned about warning before
+// they expire and deleted once they have.
+func (s *Server) StartReaper(interval, warning time.Duration) {
+	go func() {
+		s.reap(warning)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.shutdownCh:
+				return
+			case <-ticker.C:
+				s.reap(warning)
+			}
+		}
+	}()
+}
+
+// reap warns about clusters that expire within warning and deletes those
+// that have expired. Clusters busy with an operation or in a status that
+// cannot be deleted are retried on the next pass.
+func (s *Server) reap(warning time.Duration) {
+	hostState, err := s.stateManager.Load()
+	if err != nil {
+		s.logger.Warn("Reaper failed to load state", "error", err)
+		return
+	}
+
+	now := time.Now()
+	busy := s.busyClusters()
+	for name, info := range hostState.Clusters {
+		if !info.Expires(name) || busy[name] {
+			continue
+		}
+
+		switch {
+		case !now.Before(*info.ExpiresAt):
+			if state.CanTransition(info.Status, state.StatusDeleting) {
+				s.expireCluster(name, *info.ExpiresAt)
+			}
+		case !info.ExpiryWarned && !now.Add(warning).Before(*info.ExpiresAt):
+			s.warnExpiry(name, *info.ExpiresAt)
+		}
+	}
+}
+
+// warnExpiry announces the upcoming expiry of a cluster once
+func (s *Server) warnExpiry(name string, expiresAt time.Time) {
+	warned := false
+	err := s.stateManager.ModifyCluster(name, func(info *state.ClusterInfo) error {
+		// The expiry may have been changed since the state was loaded
+		if info.ExpiresAt != nil && info.ExpiresAt.Equal(expiresAt) && !info.ExpiryWarned {
+			info.ExpiryWarned = true
+			warned = true
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("Failed to record expiry warning", "cluster", name, "error", err)
+		return
+	}
+	if !warned {
+		return
+	}
+
+	message := fmt.Sprintf("Cluster %s expires at %s and will then be deleted; extend it with PATCH /clusters/%s", name, expiresAt.Format(time.RFC3339), name)
+	s.events.Publish(state.ClusterEvent{
+		Type:    state.EventExpiring,
+		Cluster: name,
+		Message: message,
+	})
+	s.auditExpiry("Cluster expiring", name, expiresAt, "")
+}
+
+// expireCluster starts deleting an expired cluster
+func (s *Server) expireCluster(name string, expiresAt time.Time) {
+	reason := "expired at " + expiresAt.Format(time.RFC3339)
+	op, err := s.operations.Start(state.OperationDeleteCluster, name, func(out io.Writer) error {
+		// The expiry may have been extended since the reaper looked
+		hostState, err := s.stateManager.Load()
+		if err != nil {
+			return err
+		}
+		info, exists := hostState.Clusters[name]
+		if !exists {
+			return nil
+		}
+		if info.ExpiresAt == nil || time.Now().Before(*info.ExpiresAt) {
+			fmt.Fprintf(out, "Expiry of cluster %s was extended; not deleting\n", name)
+			return nil
+		}
+
+		fmt.Fprintf(out, "Cluster %s %s; deleting\n", name, reason)
+		return s.deleteCluster(name, true, "", reason, out)
+	})
+	if err != nil {
+		s.logger.Warn("Failed to delete expired cluster", "cluster", name, "error", err)
+		return
+	}
+
+	s.events.Publish(state.ClusterEvent{
+		Type:      state.EventExpired,
+		Cluster:   name,
+		Action:    state.OperationDeleteCluster,
+		Operation: op.ID,
+		Message:   fmt.Sprintf("Cluster %s %s and is being deleted", name, reason),
+	})
+	s.auditExpiry("Cluster expired", name, expiresAt, op.ID)
+}
+
+// auditExpiry logs an expiry action the server took on its own, to the
+// audit log when auditing is enabled
+func (s *Server) auditExpiry(msg, name string, expiresAt time.Time, operation string) {
+	s.logger.Warn(msg, "cluster", name, "expires_at", expiresAt.Format(time.RFC3339))
+	if !s.auditEnabled {
+		return
+	}
+	s.logger.Audit(msg, map[string]string{
+		"CLUSTER":    name,
+		"EXPIRES_AT": expiresAt.Format(time.RFC3339),
+		"OPERATION":  operation,
+		"AUTH_USER":  "host-manager",
+	})
+}
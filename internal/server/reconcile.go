@@ -0,0 +1,286 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kylape/host-manager/internal/kind"
+	"github.com/kylape/host-manager/internal/state"
+)
+
+// StartReconciler compares state with kind immediately and then every
+// interval until the server shuts down. Each pass also samples the registry
+// and storage metrics.
+func (s *Server) StartReconciler(interval time.Duration) {
+	go func() {
+		s.reconcile()
+		s.sampleHostMetrics()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.shutdownCh:
+				return
+			case <-ticker.C:
+				s.reconcile()
+				s.sampleHostMetrics()
+			}
+		}
+	}()
+}
+
+// reconcile compares the clusters recorded in state with those kind and
+// podman know about. Clusters that disappeared are marked lost, clusters
+// created outside the API are recorded as unmanaged, ready clusters with
+// stopped node containers are marked degraded, and lost or degraded clusters
+// that recover are marked ready again. Clusters with an operation in flight
+// or in a transitional status are left alone.
+func (s *Server) reconcile() *state.ReconcileReport {
+	s.reconcileMu.Lock()
+	defer s.reconcileMu.Unlock()
+	// Nodes look stopped to podman while a cluster is being paused or resumed
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	start := time.Now()
+	report := &state.ReconcileReport{
+		Time:     start,
+		Clusters: []string{},
+		Findings: []state.ReconcileFinding{},
+	}
+	defer func() {
+		report.Duration = time.Since(start).Round(time.Millisecond).String()
+		s.lastReconcile.Store(report)
+		for _, finding := range report.Findings {
+			s.logger.Warn("Cluster drift detected", "cluster", finding.Cluster, "finding", finding.Finding, "status", finding.Status)
+		}
+	}()
+
+	busy := s.busyClusters()
+
+	clusters, err := s.kindClient.ListClusters()
+	if err != nil {
+		// Without a cluster list every cluster would look lost
+		report.Error = err.Error()
+		s.logger.Warn("Reconcile failed", "error", err)
+		return report
+	}
+	report.Clusters = clusters
+
+	nodes, err := s.kindClient.ListNodes()
+	if err != nil {
+		report.Warnings = append(report.Warnings, err.Error())
+		nodes = nil
+	}
+
+	// Operations started while kind was listing must not be judged on a stale list
+	for name := range s.busyClusters() {
+		busy[name] = true
+	}
+
+	inKind := make(map[string]bool)
+	for _, name := range clusters {
+		inKind[name] = true
+	}
+
+	err = s.stateManager.Update(func(hostState *state.HostState) error {
+		report.Findings = report.Findings[:0]
+
+		change := func(name string, info state.ClusterInfo, finding, to, reason string) {
+			if err := info.Transition(name, to, reason); err != nil {
+				report.Warnings = append(report.Warnings, err.Error())
+				return
+			}
+			hostState.Clusters[name] = info
+			report.Findings = append(report.Findings, state.ReconcileFinding{
+				Cluster: name,
+				Finding: finding,
+				Status:  to,
+				Message: reason,
+			})
+		}
+
+		for name, info := range hostState.Clusters {
+			if busy[name] || state.Transitional(info.Status) {
+				continue
+			}
+
+			stopped := stoppedNodes(nodes, name)
+			switch {
+			case !inKind[name] && info.Status != state.StatusLost:
+				change(name, info, state.FindingLost, state.StatusLost, fmt.Sprintf("kind no longer reports cluster (was %s)", info.Status))
+			case inKind[name] && info.Status == state.StatusLost:
+				change(name, info, state.FindingRecovered, state.StatusReady, "")
+			case info.Status == state.StatusReady && len(stopped) > 0:
+				change(name, info, state.FindingNodesStopped, state.StatusDegraded, "node containers not running: "+strings.Join(stopped, ", "))
+			case info.Status == state.StatusDegraded && nodes != nil && len(stopped) == 0:
+				change(name, info, state.FindingRecovered, state.StatusReady, "")
+			}
+		}
+
+		for _, name := range clusters {
+			if _, exists := hostState.Clusters[name]; exists || busy[name] {
+				continue
+			}
+
+			report.Findings = append(report.Findings, state.ReconcileFinding{
+				Cluster: name,
+				Finding: state.FindingUnmanaged,
+				Status:  state.StatusUnmanaged,
+				Message: "cluster was not created through host-manager",
+			})
+			clusterType := "development"
+			if name == "kind" {
+				clusterType = "infrastructure"
+			}
+			now := time.Now()
+			hostState.Clusters[name] = state.ClusterInfo{
+				Status:         state.StatusUnmanaged,
+				LastTransition: &now,
+				Type:           clusterType,
+			}
+		}
+
+		if len(report.Findings) == 0 {
+			return errNoDrift
+		}
+		return nil
+	})
+	if err != nil && err != errNoDrift {
+		report.Error = fmt.Sprintf("failed to update state: %v", err)
+		report.Findings = []state.ReconcileFinding{}
+		return report
+	}
+
+	sort.Slice(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Cluster < report.Findings[j].Cluster
+	})
+	return report
+}
+
+// errNoDrift aborts the reconcile state update when nothing changed
+var errNoDrift = errors.New("no drift")
+
+// RecoverInterrupted settles the clusters a previous run left in a
+// transitional status, judging each by its node containers: a stop that
+// completed is recorded as stopped, one that never took effect as degraded,
+// and every other cluster is marked failed. It returns the names of the
+// clusters marked failed.
+func (s *Server) RecoverInterrupted() ([]string, error) {
+	nodes, err := s.kindClient.ListNodes()
+	if err != nil {
+		s.logger.Warn("Cannot check interrupted clusters against podman", "error", err)
+		nodes = nil
+	}
+
+	var failed, recovered []string
+	err = s.stateManager.Update(func(hostState *state.HostState) error {
+		failed, recovered = nil, nil
+		for name, info := range hostState.Clusters {
+			if !state.Transitional(info.Status) {
+				continue
+			}
+			to, reason := interruptedOutcome(info.Status, nodes, name)
+			if err := info.Transition(name, to, reason); err != nil {
+				return err
+			}
+			hostState.Clusters[name] = info
+			if to == state.StatusFailed {
+				failed = append(failed, name)
+			} else {
+				recovered = append(recovered, name)
+			}
+		}
+		if len(failed) == 0 && len(recovered) == 0 {
+			return errNoDrift
+		}
+		return nil
+	})
+	if err != nil && err != errNoDrift {
+		return nil, err
+	}
+
+	for _, name := range recovered {
+		s.logger.Info("Recovered cluster interrupted by a previous shutdown", "cluster", name)
+	}
+	return failed, nil
+}
+
+// interruptedOutcome returns the status and reason to record for a cluster
+// interrupted in status from, given the node containers podman reports;
+// nodes is nil when they could not be listed
+func interruptedOutcome(from string, nodes map[string][]kind.Node, name string) (string, string) {
+	reason := "interrupted while " + from
+	if nodes == nil {
+		return state.StatusFailed, reason
+	}
+
+	running, stopped := 0, 0
+	for _, node := range nodes[name] {
+		switch {
+		case node.Running:
+			running++
+		case !node.Paused:
+			stopped++
+		}
+	}
+	total := len(nodes[name])
+
+	switch {
+	case from == state.StatusStopping && total > 0 && stopped == total:
+		return state.StatusStopped, ""
+	case from == state.StatusStopping && total > 0 && running == total:
+		return state.StatusDegraded, reason + "; node containers still running"
+	case from == state.StatusDeleting && total == 0:
+		return state.StatusFailed, reason + "; node containers already removed, delete again to release its data"
+	}
+	return state.StatusFailed, reason
+}
+
+// busyClusters returns the clusters with an operation in flight
+func (s *Server) busyClusters() map[string]bool {
+	busy := make(map[string]bool)
+	for _, op := range s.operations.Active() {
+		busy[op.Cluster] = true
+	}
+	return busy
+}
+
+// stoppedNodes returns the names of a cluster's node containers that are
+// neither running nor paused. It returns nil when node information is
+// unavailable.
+func stoppedNodes(nodes map[string][]kind.Node, cluster string) []string {
+	var stopped []string
+	for _, node := range nodes[cluster] {
+		if !node.Running && !node.Paused {
+			stopped = append(stopped, node.Name)
+		}
+	}
+	return stopped
+}
+
+// handleGetReconcile returns the report of the last reconcile pass
+func (s *Server) handleGetReconcile(w http.ResponseWriter, r *http.Request) {
+	report := s.lastReconcile.Load()
+	if report == nil {
+		http.Error(w, "No reconcile has run yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleReconcile runs a reconcile pass immediately and returns its report
+func (s *Server) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	report := s.reconcile()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -2,14 +2,24 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/kylape/host-manager/internal/addons"
+	"github.com/kylape/host-manager/internal/auth"
+	"github.com/kylape/host-manager/internal/events"
+	"github.com/kylape/host-manager/internal/host"
 	"github.com/kylape/host-manager/internal/kind"
 	"github.com/kylape/host-manager/internal/logger"
+	"github.com/kylape/host-manager/internal/operations"
 	"github.com/kylape/host-manager/internal/state"
 )
 
@@ -17,56 +27,104 @@ import (
 type Server struct {
 	stateManager *state.Manager
 	kindClient   *kind.Client
+	operations   *operations.Manager
+	events       *events.Broker
 	router       *mux.Router
 	logger       *logger.Logger
 	auditEnabled bool
+	tokens       *auth.TokenStore
+	peers        *auth.PeerPolicy
+	httpServer   *http.Server
+	metrics      *serverMetrics
+	draining     atomic.Bool
+	corsOrigins  map[string]bool
+	shutdownCh   chan struct{}
+
+	reconcileMu   sync.Mutex
+	lastReconcile atomic.Pointer[state.ReconcileReport]
+
+	healthMu sync.RWMutex
+	health   map[string]*state.ClusterHealth
+
+	apiMu      sync.Mutex
+	apiClients map[string]*apiEndpoint
+
+	// pauseMu serializes pausing and resuming clusters; idleMu guards
+	// the API server request counts of the idle monitor
+	pauseMu       sync.Mutex
+	idleMu        sync.Mutex
+	requestCounts map[string]float64
 }
 
 // New creates a new HTTP server
 func New(stateManager *state.Manager, logger *logger.Logger, auditEnabled bool) *Server {
+	broker := events.NewBroker()
 	s := &Server{
 		stateManager: stateManager,
 		kindClient:   kind.NewClient(),
+		operations:   operations.NewManager(broker),
+		events:       broker,
+		shutdownCh:   make(chan struct{}),
 		router:       mux.NewRouter(),
 		logger:       logger,
 		auditEnabled: auditEnabled,
+
+		apiClients:    make(map[string]*apiEndpoint),
+		requestCounts: make(map[string]float64),
+	}
+
+	s.httpServer = &http.Server{
+		Handler:     s.router,
+		ConnContext: s.connContext,
 	}
 
+	s.setupMetrics()
 	s.setupRoutes()
 	return s
 }
 
-// Start starts the HTTP server
-func (s *Server) Start(addr string) error {
-	s.logger.Info("Starting HTTP server", "address", addr)
-	return http.ListenAndServe(addr, s.router)
-}
-
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() {
 	// Health and status endpoints
 	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
 	s.router.HandleFunc("/host/status", s.handleHostStatus).Methods("GET")
+	s.router.HandleFunc("/host/reconcile", s.handleGetReconcile).Methods("GET")
+	s.router.HandleFunc("/host/reconcile", s.handleReconcile).Methods("POST")
 	s.router.HandleFunc("/version", s.handleVersion).Methods("GET")
+	s.router.HandleFunc("/kubernetes-versions", s.handleKubernetesVersions).Methods("GET")
+	s.router.Handle("/metrics", s.metrics.handler).Methods("GET")
 
 	// Cluster management endpoints
 	s.router.HandleFunc("/clusters", s.handleListClusters).Methods("GET")
 	s.router.HandleFunc("/clusters", s.handleCreateCluster).Methods("POST")
 	s.router.HandleFunc("/clusters/{name}", s.handleGetCluster).Methods("GET")
+	s.router.HandleFunc("/clusters/{name}", s.handleUpdateCluster).Methods("PATCH")
 	s.router.HandleFunc("/clusters/{name}", s.handleDeleteCluster).Methods("DELETE")
+	s.router.HandleFunc("/clusters/{name}/adopt", s.handleAdoptCluster).Methods("POST")
+	s.router.HandleFunc("/clusters/{name}/stop", s.handleStopCluster).Methods("POST")
+	s.router.HandleFunc("/clusters/{name}/start", s.handleStartCluster).Methods("POST")
+	s.router.HandleFunc("/clusters/{name}/restart", s.handleRestartCluster).Methods("POST")
 	s.router.HandleFunc("/clusters/{name}/kubeconfig", s.handleGetKubeconfig).Methods("GET")
 	s.router.HandleFunc("/clusters/{name}/load-image", s.handleLoadImage).Methods("POST")
+	s.router.HandleFunc("/clusters/{name}/events", s.handleClusterEvents).Methods("GET")
+
+	// Operation tracking endpoints
+	s.router.HandleFunc("/operations", s.handleListOperations).Methods("GET")
+	s.router.HandleFunc("/operations/{id}", s.handleGetOperation).Methods("GET")
 
 	// Registry management endpoints
 	s.router.HandleFunc("/registry/status", s.handleRegistryStatus).Methods("GET")
 	s.router.HandleFunc("/registry/start", s.handleRegistryStart).Methods("POST")
 
 	// Enable CORS for all routes
-	s.router.Use(corsMiddleware)
+	s.router.Use(s.corsMiddleware)
+	s.router.Use(s.metricsMiddleware)
 	s.router.Use(s.loggingMiddleware)
 	if s.auditEnabled {
 		s.router.Use(s.auditMiddleware)
 	}
+	s.router.Use(s.authMiddleware)
+	s.router.Use(s.drainMiddleware)
 }
 
 // handleHealth returns service health status
@@ -110,8 +168,33 @@ func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleListClusters returns all clusters
+// handleKubernetesVersions returns the Kubernetes versions clusters can be
+// created with
+func (s *Server) handleKubernetesVersions(w http.ResponseWriter, r *http.Request) {
+	response := state.KubernetesVersionsResponse{
+		Default:  kind.DefaultKubernetesVersion,
+		Versions: []state.KubernetesVersion{},
+	}
+	for _, image := range kind.NodeImages() {
+		response.Versions = append(response.Versions, state.KubernetesVersion{
+			Version: image.Version,
+			Image:   image.Image,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleListClusters returns all clusters, or those matching the label
+// selector given as ?selector=
 func (s *Server) handleListClusters(w http.ResponseWriter, r *http.Request) {
+	selector, err := state.ParseSelector(r.URL.Query().Get("selector"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	hostState, err := s.stateManager.Load()
 	if err != nil {
 		http.Error(w, "Failed to load host state", http.StatusInternalServerError)
@@ -120,13 +203,12 @@ func (s *Server) handleListClusters(w http.ResponseWriter, r *http.Request) {
 
 	var clusters []state.ClusterResponse
 	for name, info := range hostState.Clusters {
-		clusters = append(clusters, state.ClusterResponse{
-			Name:     name,
-			Status:   info.Status,
-			Created:  info.Created,
-			Type:     info.Type,
-			KubeVirt: info.KubeVirt,
-		})
+		if !selector.Matches(info.Labels) {
+			continue
+		}
+		cluster := clusterResponse(name, info)
+		cluster.Health = s.cachedHealth(name)
+		clusters = append(clusters, cluster)
 	}
 
 	response := map[string][]state.ClusterResponse{
@@ -145,52 +227,171 @@ func (s *Server) handleCreateCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Name == "" {
-		http.Error(w, "Cluster name is required", http.StatusBadRequest)
+	if err := state.ValidateClusterName(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Check if cluster already exists
-	hostState, err := s.stateManager.Load()
+	image, err := kind.ResolveNodeImage(req.KubernetesVersion, req.NodeImage, callerIsAdmin(r))
+	if errors.Is(err, kind.ErrCustomImage) {
+		http.Error(w, err.Error()+" (only admins may run other node images)", http.StatusForbidden)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to load host state", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	topology, err := state.NormalizeTopology(req.Topology)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid topology: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	if _, exists := hostState.Clusters[req.Name]; exists {
-		http.Error(w, fmt.Sprintf("Cluster %s already exists", req.Name), http.StatusConflict)
+	ports, err := state.ClusterPorts(req.NodePorts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Create the cluster
-	if err := s.kindClient.CreateCluster(req.Name, true); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create cluster: %v", err), http.StatusInternalServerError)
+	installs, err := addons.Resolve(req.Addons)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid addons: %v", err), http.StatusBadRequest)
 		return
 	}
+	var addonStatus []state.AddonStatus
+	for _, install := range installs {
+		addonStatus = append(addonStatus, state.AddonStatus{
+			Name:    install.Name,
+			Version: install.Version,
+			Params:  install.Params,
+			Status:  state.AddonPending,
+		})
+		for _, port := range install.Ports {
+			ports = append(ports, state.PortMapping{Purpose: state.PortIngress, ContainerPort: port})
+		}
+		// Normalized topologies list the first control-plane node first
+		for key, value := range install.NodeLabels {
+			if topology.Nodes[0].Labels == nil {
+				topology.Nodes[0].Labels = map[string]string{}
+			}
+			topology.Nodes[0].Labels[key] = value
+		}
+	}
+
+	if req.KubeVirt {
+		if err := host.CheckKVM(); err != nil {
+			http.Error(w, fmt.Sprintf("KubeVirt is not available on this host: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	mounts, err := state.NormalizeMounts(req.Mounts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid mounts: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !state.ValidDataPolicy(req.DataPolicy) {
+		http.Error(w, fmt.Sprintf("Invalid data_policy %q: use %s or %s", req.DataPolicy, state.DataDelete, state.DataRetain), http.StatusBadRequest)
+		return
+	}
+	dataPolicy := req.DataPolicy
+	if dataPolicy == "" {
+		dataPolicy = state.DataDelete
+	}
+
+	expiresAt, err := state.ExpiryTime(req.TTL, req.ExpiresAt, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := state.ValidateLabels(req.Labels); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := state.ValidateAnnotations(req.Annotations); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	owner := req.Owner
+	if identity, ok := auth.FromContext(r.Context()); owner == "" && ok {
+		owner = identity.Name
+	}
 
-	// Update state
 	clusterType := "development"
 	if req.Name == "kind" {
 		clusterType = "infrastructure"
+		if expiresAt != nil {
+			http.Error(w, "The infrastructure cluster cannot expire", http.StatusBadRequest)
+			return
+		}
 	}
 
-	if err := s.stateManager.UpdateCluster(req.Name, "running", clusterType, req.KubeVirt); err != nil {
-		log.Printf("Failed to update cluster state: %v", err)
+	// Record the cluster before kind runs so a restart mid-create is detected
+	info := state.ClusterInfo{
+		Type:              clusterType,
+		KubeVirt:          req.KubeVirt,
+		KubernetesVersion: image.Version,
+		NodeImage:         image.Image,
+		Topology:          topology,
+		Ports:             ports,
+		Mounts:            mounts,
+		DataPolicy:        dataPolicy,
+		Addons:            addonStatus,
+		ExpiresAt:         expiresAt,
+		Owner:             owner,
+		Labels:            req.Labels,
+		Annotations:       req.Annotations,
+	}
+	if err := s.stateManager.AddCluster(req.Name, info); err != nil {
+		if errors.Is(err, state.ErrClusterExists) {
+			http.Error(w, fmt.Sprintf("Cluster %s already exists", req.Name), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, state.ErrPortsExhausted) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Failed to update host state", http.StatusInternalServerError)
+		return
 	}
 
-	response := map[string]interface{}{
-		"success": true,
-		"cluster": state.ClusterResponse{
-			Name:     req.Name,
-			Status:   "running",
-			Type:     clusterType,
-			KubeVirt: req.KubeVirt,
-		},
+	// Create the cluster in the background
+	op, err := s.operations.Start(state.OperationCreateCluster, req.Name, func(out io.Writer) error {
+		return s.createCluster(req.Name, req.KeepOnFailure, out)
+	})
+	if err != nil {
+		if err := s.stateManager.RemoveCluster(req.Name); err != nil {
+			log.Printf("Failed to remove cluster from state: %v", err)
+		}
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	writeOperation(w, op)
+}
+
+// failureReason condenses an operation error into a one-line status reason
+func failureReason(err error) string {
+	reason, _, _ := strings.Cut(err.Error(), "\n")
+	return reason
+}
+
+// clusterStatus returns the recorded status of a cluster, or "" if the
+// cluster is not in state
+func (s *Server) clusterStatus(name string) (string, error) {
+	hostState, err := s.stateManager.Load()
+	if err != nil {
+		return "", err
+	}
+	return hostState.Clusters[name].Status, nil
+}
+
+// writeTransitionError responds with 409 Conflict for an action the
+// cluster's current status does not allow
+func writeTransitionError(w http.ResponseWriter, name, status, action string) {
+	http.Error(w, fmt.Sprintf("Cluster %s is %s; %s is not allowed in this state", name, status, action), http.StatusConflict)
 }
 
 // handleGetCluster returns details for a specific cluster
@@ -210,18 +411,123 @@ func (s *Server) handleGetCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := state.ClusterResponse{
-		Name:     name,
-		Status:   info.Status,
-		Created:  info.Created,
-		Type:     info.Type,
-		KubeVirt: info.KubeVirt,
+	response := clusterResponse(name, info)
+	response.Health = s.cachedHealth(name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleUpdateCluster changes the expiry, owner, labels and annotations of
+// a cluster
+func (s *Server) handleUpdateCluster(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	var req state.ClusterUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	expiresAt, err := state.ExpiryTime(req.TTL, req.ExpiresAt, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.NoExpiry && expiresAt != nil {
+		http.Error(w, "no_expiry cannot be combined with ttl or expires_at", http.StatusBadRequest)
+		return
+	}
+	changeExpiry := req.NoExpiry || expiresAt != nil
+	if changeExpiry && name == "kind" {
+		http.Error(w, "The infrastructure cluster cannot expire", http.StatusForbidden)
+		return
 	}
 
+	var info state.ClusterInfo
+	err = s.stateManager.ModifyCluster(name, func(recorded *state.ClusterInfo) error {
+		labels := state.ApplyMapPatch(recorded.Labels, req.Labels)
+		if err := state.ValidateLabels(labels); err != nil {
+			return &invalidUpdateError{err}
+		}
+		annotations := state.ApplyMapPatch(recorded.Annotations, req.Annotations)
+		if err := state.ValidateAnnotations(annotations); err != nil {
+			return &invalidUpdateError{err}
+		}
+
+		recorded.Labels = labels
+		recorded.Annotations = annotations
+		if req.Owner != nil {
+			recorded.Owner = *req.Owner
+		}
+		if changeExpiry {
+			recorded.ExpiresAt = expiresAt
+			recorded.ExpiryWarned = false
+		}
+		info = *recorded
+		return nil
+	})
+	var invalid *invalidUpdateError
+	if errors.As(err, &invalid) {
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, state.ErrClusterNotFound) {
+		http.Error(w, "Cluster not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update host state", http.StatusInternalServerError)
+		return
+	}
+
+	response := clusterResponse(name, info)
+	response.Health = s.cachedHealth(name)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// invalidUpdateError rejects an update whose result would be invalid
+type invalidUpdateError struct {
+	err error
+}
+
+func (e *invalidUpdateError) Error() string {
+	return e.err.Error()
+}
+
+// clusterResponse converts a state entry into its API representation
+func clusterResponse(name string, info state.ClusterInfo) state.ClusterResponse {
+	return state.ClusterResponse{
+		Name:              name,
+		Status:            info.Status,
+		StatusReason:      info.StatusReason,
+		LastTransition:    info.LastTransition,
+		Created:           info.Created,
+		Type:              info.Type,
+		KubeVirt:          info.KubeVirt,
+		Adopted:           info.Adopted,
+		KubernetesVersion: info.KubernetesVersion,
+		NodeImage:         info.NodeImage,
+		Nodes:             info.Nodes,
+		Registry:          info.Registry,
+		FailureLogs:       info.FailureLogs,
+		Topology:          info.Topology,
+		Ports:             info.Ports,
+		DataDir:           info.DataDir,
+		DataPolicy:        info.DataPolicy,
+		Mounts:            info.Mounts,
+		Addons:            info.Addons,
+		ExpiresAt:         info.ExpiresAt,
+		LastActivity:      info.LastActivity,
+		Owner:             info.Owner,
+		Labels:            info.Labels,
+		Annotations:       info.Annotations,
+	}
+}
+
 // handleDeleteCluster deletes a cluster
 func (s *Server) handleDeleteCluster(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -232,20 +538,145 @@ func (s *Server) handleDeleteCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete the cluster
-	if err := s.kindClient.DeleteCluster(name); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete cluster: %v", err), http.StatusInternalServerError)
+	// The data policy chosen at create can be overridden per delete
+	dataPolicy := r.URL.Query().Get("data")
+	if !state.ValidDataPolicy(dataPolicy) {
+		http.Error(w, fmt.Sprintf("Invalid data policy %q: use %s or %s", dataPolicy, state.DataDelete, state.DataRetain), http.StatusBadRequest)
+		return
+	}
+
+	status, err := s.clusterStatus(name)
+	if err != nil {
+		http.Error(w, "Failed to load host state", http.StatusInternalServerError)
+		return
+	}
+	if status != "" && !state.CanTransition(status, state.StatusDeleting) {
+		writeTransitionError(w, name, status, "delete")
+		return
+	}
+
+	// Delete the cluster in the background
+	op, err := s.operations.Start(state.OperationDeleteCluster, name, func(out io.Writer) error {
+		// Clusters unknown to the state file are still deleted from kind
+		return s.deleteCluster(name, status != "", dataPolicy, "", out)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
 		return
 	}
 
-	// Remove from state
-	if err := s.stateManager.RemoveCluster(name); err != nil {
-		log.Printf("Failed to remove cluster from state: %v", err)
+	writeOperation(w, op)
+}
+
+// deleteCluster runs a delete operation. A tracked cluster is moved through
+// deleting with reason recorded, its data released per dataPolicy (its own
+// policy if empty) and its record removed.
+func (s *Server) deleteCluster(name string, tracked bool, dataPolicy, reason string, out io.Writer) error {
+	var info state.ClusterInfo
+	if tracked {
+		err := s.stateManager.ModifyCluster(name, func(recorded *state.ClusterInfo) error {
+			info = *recorded
+			return recorded.Transition(name, state.StatusDeleting, reason)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update cluster state: %w", err)
+		}
+	}
+	if info.Status == state.StatusPaused {
+		if err := s.kindClient.UnpauseCluster(name, out); err != nil {
+			fmt.Fprintf(out, "Failed to unpause cluster %s: %v\n", name, err)
+		}
+	}
+
+	if err := s.kindClient.DeleteCluster(name, out); err != nil {
+		if tracked && !s.aborted() {
+			if err := s.stateManager.TransitionCluster(name, state.StatusFailed, failureReason(err)); err != nil {
+				log.Printf("Failed to record cluster failure: %v", err)
+			}
+		}
+		return err
+	}
+	s.dropAPIClient(name)
+
+	if tracked {
+		if dataPolicy == "" {
+			dataPolicy = info.DataPolicy
+		}
+		s.releaseClusterData(name, info, dataPolicy, out)
+		if err := s.stateManager.TransitionCluster(name, state.StatusDeleted, ""); err != nil {
+			return fmt.Errorf("failed to remove cluster from state: %w", err)
+		}
+	}
+	return nil
+}
+
+// handleAdoptCluster takes over management of a kind cluster that was
+// created outside the API
+func (s *Server) handleAdoptCluster(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	// The name reaches kind and podman, and becomes a data directory
+	if err := state.ValidateClusterName(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.busyClusters()[name] {
+		http.Error(w, fmt.Sprintf("Cluster %s has an operation in progress", name), http.StatusConflict)
+		return
+	}
+
+	details, err := s.kindClient.InspectCluster(name)
+	if errors.Is(err, kind.ErrClusterNotFound) {
+		http.Error(w, fmt.Sprintf("Cluster %s not found in kind", name), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to inspect cluster: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	clusterType := "development"
+	if name == "kind" {
+		clusterType = "infrastructure"
+	}
+	info := state.ClusterInfo{
+		Created:           details.Created,
+		Type:              clusterType,
+		KubernetesVersion: details.KubernetesVersion,
+		NodeImage:         details.NodeImage,
+		Registry:          details.Registry,
+	}
+	info.Topology = &state.Topology{}
+	for _, node := range details.Nodes {
+		info.Nodes = append(info.Nodes, node.Name)
+		switch node.Role {
+		case state.RoleControlPlane:
+			info.Topology.ControlPlanes++
+		case state.RoleWorker:
+			info.Topology.Workers++
+		}
+	}
+
+	if err := s.stateManager.AdoptCluster(name, info); err != nil {
+		if errors.Is(err, state.ErrClusterManaged) {
+			http.Error(w, fmt.Sprintf("Cluster %s is already managed", name), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update host state", http.StatusInternalServerError)
+		return
+	}
+
+	hostState, err := s.stateManager.Load()
+	if err != nil {
+		http.Error(w, "Failed to load host state", http.StatusInternalServerError)
+		return
 	}
 
 	response := map[string]interface{}{
 		"success": true,
-		"message": fmt.Sprintf("Cluster %s deleted", name),
+		"message": fmt.Sprintf("Cluster %s adopted", name),
+		"cluster": clusterResponse(name, hostState.Clusters[name]),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -257,6 +688,20 @@ func (s *Server) handleGetKubeconfig(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
 
+	status, err := s.clusterStatus(name)
+	if err != nil {
+		http.Error(w, "Failed to load host state", http.StatusInternalServerError)
+		return
+	}
+	if status != "" && !state.Usable(status) && status != state.StatusPaused {
+		writeTransitionError(w, name, status, "kubeconfig")
+		return
+	}
+	if err := s.useCluster(name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	kubeconfig, err := s.kindClient.GetKubeconfig(name)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get kubeconfig: %v", err), http.StatusInternalServerError)
@@ -286,7 +731,43 @@ func (s *Server) handleLoadImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.kindClient.LoadImage(name, req.Image); err != nil {
+	status, err := s.clusterStatus(name)
+	if err != nil {
+		http.Error(w, "Failed to load host state", http.StatusInternalServerError)
+		return
+	}
+	if status != "" && !state.Usable(status) && status != state.StatusPaused {
+		writeTransitionError(w, name, status, "load-image")
+		return
+	}
+	if err := s.useCluster(name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.events.Publish(state.ClusterEvent{
+		Type:    state.EventStarted,
+		Cluster: name,
+		Action:  state.OperationLoadImage,
+		Message: req.Image,
+	})
+	lines := s.events.NewLineWriter(name, state.OperationLoadImage, "")
+	err = s.kindClient.LoadImage(name, req.Image, lines)
+	lines.Flush()
+
+	completed := state.ClusterEvent{
+		Type:    state.EventCompleted,
+		Cluster: name,
+		Action:  state.OperationLoadImage,
+		Phase:   state.OperationSucceeded,
+	}
+	if err != nil {
+		completed.Phase = state.OperationFailed
+		completed.Error = err.Error()
+	}
+	s.events.Publish(completed)
+
+	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to load image: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -300,11 +781,103 @@ func (s *Server) handleLoadImage(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleClusterEvents streams cluster events as Server-Sent Events. The
+// events of the cluster's most recent action are replayed first, followed by
+// live events until the client disconnects.
+func (s *Server) handleClusterEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	history, ch, unsubscribe := s.events.Subscribe(name)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, event := range history {
+		writeEvent(w, event)
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shutdownCh:
+			return
+		case event := <-ch:
+			writeEvent(w, event)
+			flusher.Flush()
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes a single event in Server-Sent Events format
+func writeEvent(w io.Writer, event state.ClusterEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
+
+// handleListOperations returns all tracked operations
+func (s *Server) handleListOperations(w http.ResponseWriter, r *http.Request) {
+	response := map[string][]state.Operation{
+		"operations": s.operations.List(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetOperation returns a single operation including its captured output
+func (s *Server) handleGetOperation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	op, exists := s.operations.Get(id)
+	if !exists {
+		http.Error(w, "Operation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(op)
+}
+
+// writeOperation responds with 202 Accepted and the operation resource
+func writeOperation(w http.ResponseWriter, op *state.Operation) {
+	response := map[string]interface{}{
+		"success":   true,
+		"operation": op,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/operations/"+op.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleRegistryStatus returns registry status
 func (s *Server) handleRegistryStatus(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement actual registry status check
 	response := state.RegistryStatus{
-		Running: true,
+		Running: s.kindClient.RegistryRunning(),
 		Port:    5001,
 		URL:     "localhost:5001",
 	}
@@ -315,7 +888,7 @@ func (s *Server) handleRegistryStatus(w http.ResponseWriter, r *http.Request) {
 
 // handleRegistryStart starts the registry
 func (s *Server) handleRegistryStart(w http.ResponseWriter, r *http.Request) {
-	if err := s.kindClient.CreateRegistry(); err != nil {
+	if err := s.kindClient.CreateRegistry(nil); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to start registry: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -333,17 +906,39 @@ func (s *Server) handleRegistryStart(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// corsMiddleware adds CORS headers
-func corsMiddleware(next http.Handler) http.Handler {
+// SetCORSOrigins sets the browser origins allowed to call the API. It must
+// be called before Start.
+func (s *Server) SetCORSOrigins(origins []string) {
+	s.corsOrigins = make(map[string]bool)
+	for _, origin := range origins {
+		s.corsOrigins[origin] = true
+	}
+}
+
+// corsMiddleware adds CORS headers for allowed origins. Requests from any
+// other origin may only read: browsers send simple POST requests without a
+// preflight, so mutating requests are refused here instead.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			w.Header().Add("Vary", "Origin")
+		}
+		allowed := origin != "" && s.corsOrigins[origin]
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		}
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
+		if origin != "" && !allowed && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Origin "+origin+" is not allowed", http.StatusForbidden)
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	})
@@ -368,19 +963,27 @@ func (s *Server) auditMiddleware(next http.Handler) http.Handler {
 		// Process the request
 		next.ServeHTTP(wrapped, r)
 
+		// Record who made the request, if they presented a valid token
+		authUser, authRole := "anonymous", ""
+		if identity, ok := s.authenticate(r); ok {
+			authUser, authRole = identity.Name, identity.Role
+		}
+
 		// Log audit information
 		duration := time.Since(start)
 		s.logger.Audit("HTTP request processed", map[string]string{
-			"HTTP_METHOD":     r.Method,
-			"HTTP_PATH":       r.URL.Path,
-			"HTTP_QUERY":      r.URL.RawQuery,
-			"CLIENT_IP":       getClientIP(r),
-			"USER_AGENT":      r.UserAgent(),
-			"REFERER":         r.Referer(),
-			"STATUS_CODE":     fmt.Sprintf("%d", wrapped.statusCode),
-			"RESPONSE_TIME":   duration.String(),
-			"CONTENT_LENGTH":  r.Header.Get("Content-Length"),
-			"REQUEST_ID":      fmt.Sprintf("%d", start.UnixNano()),
+			"HTTP_METHOD":    r.Method,
+			"HTTP_PATH":      r.URL.Path,
+			"HTTP_QUERY":     r.URL.RawQuery,
+			"CLIENT_IP":      getClientIP(r),
+			"USER_AGENT":     r.UserAgent(),
+			"REFERER":        r.Referer(),
+			"STATUS_CODE":    fmt.Sprintf("%d", wrapped.statusCode),
+			"RESPONSE_TIME":  duration.String(),
+			"CONTENT_LENGTH": r.Header.Get("Content-Length"),
+			"REQUEST_ID":     fmt.Sprintf("%d", start.UnixNano()),
+			"AUTH_USER":      authUser,
+			"AUTH_ROLE":      authRole,
 		})
 	})
 }
@@ -396,6 +999,13 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush implements http.Flusher so streaming handlers work behind the middleware
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // getClientIP extracts the real client IP from headers
 func getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header first
@@ -408,4 +1018,4 @@ func getClientIP(r *http.Request) string {
 	}
 	// Fall back to RemoteAddr
 	return r.RemoteAddr
-}
\ No newline at end of file
+}
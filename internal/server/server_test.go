@@ -0,0 +1,24 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestAdoptClusterValidatesName(t *testing.T) {
+	// No kind client: an invalid name must be refused before kind runs
+	s := &Server{}
+	router := mux.NewRouter()
+	router.HandleFunc("/clusters/{name}/adopt", s.handleAdoptCluster).Methods("POST")
+
+	for _, name := range []string{"Dev", "dev_1", "-dev", "dev.1"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/clusters/"+name+"/adopt", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("adopt %q returned %d, want %d", name, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
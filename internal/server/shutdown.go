@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// closeTimeout bounds how long closing idle connections may take once
+// operations have drained
+const closeTimeout = 5 * time.Second
+
+// abortTimeout bounds how long aborted operations may take to wind down
+// once their commands have been terminated
+const abortTimeout = 30 * time.Second
+
+// Shutdown stops the server gracefully. New mutating requests are rejected
+// immediately, running cluster operations are given until ctx is done to
+// finish, and any that are still running afterwards have their kind and
+// podman commands terminated. Once those operations have stopped, their
+// clusters are marked interrupted in state. Finally the listeners are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
+	close(s.shutdownCh)
+
+	active := s.operations.Active()
+	if len(active) > 0 {
+		s.logger.Info("Waiting for running operations", "count", len(active))
+	}
+
+	if err := s.operations.Wait(ctx); err != nil {
+		interrupted := s.operations.Active()
+		for _, op := range interrupted {
+			s.logger.Warn("Operation interrupted by shutdown", "operation", op.ID, "type", op.Type, "cluster", op.Cluster)
+		}
+
+		// Operations must not update a cluster after it is marked interrupted
+		s.kindClient.Abort()
+		abortCtx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+		if err := s.operations.Wait(abortCtx); err != nil {
+			s.logger.Error("Operations still running after their commands were terminated", "count", len(s.operations.Active()))
+		}
+		cancel()
+
+		for _, op := range interrupted {
+			if _, err := s.stateManager.MarkInterrupted(op.Cluster); err != nil {
+				s.logger.Error("Failed to mark cluster interrupted", "cluster", op.Cluster, "error", err)
+			}
+		}
+	}
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if err := s.httpServer.Shutdown(closeCtx); err != nil {
+		return s.httpServer.Close()
+	}
+	return nil
+}
+
+// aborted reports whether shutdown has terminated the running commands.
+// An operation failing after that leaves its cluster in its transitional
+// status, for Shutdown to mark it interrupted, rather than rolling it back
+// or recording the failure the abort caused.
+func (s *Server) aborted() bool {
+	select {
+	case <-s.kindClient.Aborted():
+		return true
+	default:
+		return false
+	}
+}
+
+// drainMiddleware rejects mutating requests once shutdown has begun
+func (s *Server) drainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.draining.Load() && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Retry-After", "30")
+			http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
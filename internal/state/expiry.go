@@ -0,0 +1,42 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExpiryTime returns the expiry set by a TTL or an absolute time, or nil if
+// both are empty. Only one may be given, and the result must lie after now.
+func ExpiryTime(ttl string, expiresAt *time.Time, now time.Time) (*time.Time, error) {
+	if ttl != "" && expiresAt != nil {
+		return nil, fmt.Errorf("give either ttl or expires_at, not both")
+	}
+
+	if ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl %q: use a duration such as 90m or 8h", ttl)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("ttl must be positive, got %s", ttl)
+		}
+		t := now.Add(d).UTC()
+		return &t, nil
+	}
+
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, fmt.Errorf("expires_at %s is in the past", expiresAt.Format(time.RFC3339))
+		}
+		t := expiresAt.UTC()
+		return &t, nil
+	}
+
+	return nil, nil
+}
+
+// Expires reports whether the reaper may delete a cluster when it expires.
+// The infrastructure cluster never expires.
+func (info ClusterInfo) Expires(name string) bool {
+	return info.ExpiresAt != nil && name != "kind" && info.Type != "infrastructure"
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiryTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(t time.Time) *time.Time { return &t }
+	local := time.FixedZone("CEST", 2*60*60)
+
+	tests := []struct {
+		ttl       string
+		expiresAt *time.Time
+		want      *time.Time
+		err       string // empty when the expiry is valid
+	}{
+		{"", nil, nil, ""},
+		{"90m", nil, at(now.Add(90 * time.Minute)), ""},
+		{"8h", nil, at(now.Add(8 * time.Hour)), ""},
+		{"1h30m", nil, at(now.Add(90 * time.Minute)), ""},
+		{"", at(now.Add(time.Hour)), at(now.Add(time.Hour)), ""},
+		{"", at(time.Date(2024, 5, 1, 16, 0, 0, 0, local)), at(time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)), ""},
+		{"2d", nil, nil, "invalid ttl"},
+		{"soon", nil, nil, "invalid ttl"},
+		{"0s", nil, nil, "must be positive"},
+		{"-1h", nil, nil, "must be positive"},
+		{"", at(now), nil, "in the past"},
+		{"", at(now.Add(-time.Minute)), nil, "in the past"},
+		{"1h", at(now.Add(time.Hour)), nil, "not both"},
+	}
+	for _, test := range tests {
+		got, err := ExpiryTime(test.ttl, test.expiresAt, now)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ExpiryTime(%q, %v) = %v, want an error containing %q", test.ttl, test.expiresAt, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ExpiryTime(%q, %v) = %v", test.ttl, test.expiresAt, err)
+			continue
+		}
+		if (got == nil) != (test.want == nil) || (got != nil && (!got.Equal(*test.want) || got.Location() != time.UTC)) {
+			t.Errorf("ExpiryTime(%q, %v) = %v, want %v in UTC", test.ttl, test.expiresAt, got, test.want)
+		}
+	}
+}
+
+func TestExpires(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name string
+		info ClusterInfo
+		want bool
+	}{
+		{"dev", ClusterInfo{Type: "development", ExpiresAt: &soon}, true},
+		{"dev", ClusterInfo{Type: "development"}, false},
+		{"kind", ClusterInfo{Type: "infrastructure", ExpiresAt: &soon}, false},
+		{"kind", ClusterInfo{Type: "development", ExpiresAt: &soon}, false},
+		{"infra", ClusterInfo{Type: "infrastructure", ExpiresAt: &soon}, false},
+	}
+	for _, test := range tests {
+		if got := test.info.Expires(test.name); got != test.want {
+			t.Errorf("%s %+v expires = %v, want %v", test.name, test.info, got, test.want)
+		}
+	}
+}
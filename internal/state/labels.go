@@ -0,0 +1,164 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Label and annotation keys follow Kubernetes: an optional DNS subdomain
+// prefix and a name of up to 63 characters
+var (
+	labelName   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelPrefix = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+)
+
+// maxAnnotationsSize bounds the total size of a cluster's annotations
+const maxAnnotationsSize = 256 * 1024
+
+// validKey reports whether key is a valid label or annotation key
+func validKey(key string) bool {
+	prefix, name, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix {
+		return labelName.MatchString(key)
+	}
+	return labelPrefix.MatchString(prefix) && labelName.MatchString(name)
+}
+
+// ValidateLabels checks label keys and values, which like Kubernetes labels
+// are limited to 63 characters of letters, digits, '-', '_' and '.'
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if !validKey(key) {
+			return fmt.Errorf("invalid label key %q", key)
+		}
+		if value != "" && !labelName.MatchString(value) {
+			return fmt.Errorf("invalid value %q for label %s", value, key)
+		}
+	}
+	return nil
+}
+
+// ValidateAnnotations checks annotation keys and the total size; values
+// are free-form
+func ValidateAnnotations(annotations map[string]string) error {
+	size := 0
+	for key, value := range annotations {
+		if !validKey(key) {
+			return fmt.Errorf("invalid annotation key %q", key)
+		}
+		size += len(key) + len(value)
+	}
+	if size > maxAnnotationsSize {
+		return fmt.Errorf("annotations total %d bytes; the limit is %d", size, maxAnnotationsSize)
+	}
+	return nil
+}
+
+// Selector operators
+const (
+	selectEquals    = "="
+	selectNotEquals = "!="
+	selectExists    = "exists"
+	selectNotExists = "!"
+)
+
+// requirement is one comma-separated term of a label selector
+type requirement struct {
+	key      string
+	operator string
+	value    string
+}
+
+// Selector filters clusters by their labels
+type Selector []requirement
+
+// ParseSelector parses a label selector of comma-separated requirements:
+// key=value (or key==value), key!=value, key (the label is set) and !key
+// (the label is not set). An empty selector matches every cluster.
+func ParseSelector(selector string) (Selector, error) {
+	var parsed Selector
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var req requirement
+		switch {
+		case strings.Contains(term, "!="):
+			req.key, req.value, _ = strings.Cut(term, "!=")
+			req.operator = selectNotEquals
+		case strings.Contains(term, "=="):
+			req.key, req.value, _ = strings.Cut(term, "==")
+			req.operator = selectEquals
+		case strings.Contains(term, "="):
+			req.key, req.value, _ = strings.Cut(term, "=")
+			req.operator = selectEquals
+		case strings.HasPrefix(term, "!"):
+			req.key = term[1:]
+			req.operator = selectNotExists
+		default:
+			req.key = term
+			req.operator = selectExists
+		}
+
+		req.key = strings.TrimSpace(req.key)
+		req.value = strings.TrimSpace(req.value)
+		if !validKey(req.key) {
+			return nil, fmt.Errorf("invalid label key %q in selector", req.key)
+		}
+		if req.value != "" && !labelName.MatchString(req.value) {
+			return nil, fmt.Errorf("invalid label value %q in selector", req.value)
+		}
+		parsed = append(parsed, req)
+	}
+	return parsed, nil
+}
+
+// Matches reports whether labels satisfy every requirement of the selector
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		value, ok := labels[req.key]
+		switch req.operator {
+		case selectEquals:
+			if !ok || value != req.value {
+				return false
+			}
+		case selectNotEquals:
+			if ok && value == req.value {
+				return false
+			}
+		case selectExists:
+			if !ok {
+				return false
+			}
+		case selectNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ApplyMapPatch applies a patch to a label or annotation map: each key is
+// set to its value, or removed if the value is nil. It returns the result,
+// nil if empty.
+func ApplyMapPatch(current map[string]string, patch map[string]*string) map[string]string {
+	result := make(map[string]string, len(current))
+	for key, value := range current {
+		result[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(result, key)
+		} else {
+			result[key] = *value
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"team": "infra", "env": "ci", "example.com/owner": "alice", "empty": ""}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"team=infra", true},
+		{"team==infra", true},
+		{"team=web", false},
+		{"team!=web", true},
+		{"team!=infra", false},
+		{"missing!=x", true},
+		{"team", true},
+		{"missing", false},
+		{"!missing", true},
+		{"!team", false},
+		{"empty", true},
+		{"empty=", true},
+		{"example.com/owner=alice", true},
+		{"team=infra,env=ci", true},
+		{"team=infra,env=prod", false},
+		{" team = infra , !missing ", true},
+		{"team=infra,,env", true},
+	}
+	for _, test := range tests {
+		selector, err := ParseSelector(test.selector)
+		if err != nil {
+			t.Errorf("ParseSelector(%q) = %v", test.selector, err)
+			continue
+		}
+		if got := selector.Matches(labels); got != test.want {
+			t.Errorf("%q matches %v = %v, want %v", test.selector, labels, got, test.want)
+		}
+	}
+
+	// Without labels only negative requirements match
+	for selector, want := range map[string]bool{"team": false, "team=infra": false, "!team": true, "team!=infra": true} {
+		parsed, _ := ParseSelector(selector)
+		if got := parsed.Matches(nil); got != want {
+			t.Errorf("%q matches no labels = %v, want %v", selector, got, want)
+		}
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	invalid := []string{
+		"=infra",
+		"!",
+		"team=in fra",
+		"team=a/b",
+		"bad key=x",
+		"-team",
+		"Example.com/owner=alice",
+		"team=" + strings.Repeat("a", 64),
+	}
+	for _, selector := range invalid {
+		if _, err := ParseSelector(selector); err == nil {
+			t.Errorf("ParseSelector(%q) succeeded", selector)
+		}
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		valid  bool
+	}{
+		{nil, true},
+		{map[string]string{"team": "infra", "empty": "", "a.b_c-d": "X.y_z-1"}, true},
+		{map[string]string{"example.com/team": "infra"}, true},
+		{map[string]string{strings.Repeat("k", 63): strings.Repeat("v", 63)}, true},
+		{map[string]string{"": "x"}, false},
+		{map[string]string{strings.Repeat("k", 64): "x"}, false},
+		{map[string]string{"/team": "x"}, false},
+		{map[string]string{"a/b/c": "x"}, false},
+		{map[string]string{"_team": "x"}, false},
+		{map[string]string{"team": "-x"}, false},
+		{map[string]string{"team": "a b"}, false},
+	}
+	for _, test := range tests {
+		if err := ValidateLabels(test.labels); (err == nil) != test.valid {
+			t.Errorf("ValidateLabels(%v) = %v, want valid %v", test.labels, err, test.valid)
+		}
+	}
+}
+
+func TestValidateAnnotations(t *testing.T) {
+	if err := ValidateAnnotations(map[string]string{"example.com/note": "any text: with spaces\nand lines"}); err != nil {
+		t.Errorf("free-form annotation value refused: %v", err)
+	}
+	if err := ValidateAnnotations(map[string]string{"bad key": "x"}); err == nil {
+		t.Error("invalid annotation key accepted")
+	}
+	if err := ValidateAnnotations(map[string]string{"big": strings.Repeat("x", maxAnnotationsSize)}); err == nil {
+		t.Error("annotations over the size limit accepted")
+	}
+}
+
+func TestApplyMapPatch(t *testing.T) {
+	value := func(s string) *string { return &s }
+
+	tests := []struct {
+		current map[string]string
+		patch   map[string]*string
+		want    map[string]string
+	}{
+		{nil, nil, nil},
+		{nil, map[string]*string{"team": value("infra")}, map[string]string{"team": "infra"}},
+		{map[string]string{"team": "infra"}, map[string]*string{"team": value("web")}, map[string]string{"team": "web"}},
+		{map[string]string{"team": "infra", "env": "ci"}, map[string]*string{"env": nil}, map[string]string{"team": "infra"}},
+		{map[string]string{"team": "infra"}, map[string]*string{"team": nil}, nil},
+		{map[string]string{"team": "infra"}, map[string]*string{"missing": nil}, map[string]string{"team": "infra"}},
+		{map[string]string{"team": "infra"}, map[string]*string{"note": value("")}, map[string]string{"team": "infra", "note": ""}},
+	}
+	for _, test := range tests {
+		before := fmt.Sprint(test.current)
+		got := ApplyMapPatch(test.current, test.patch)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) || (got == nil) != (test.want == nil) {
+			t.Errorf("ApplyMapPatch(%v, %v) = %v, want %v", test.current, test.patch, got, test.want)
+		}
+		if fmt.Sprint(test.current) != before {
+			t.Errorf("ApplyMapPatch modified its input: %v", test.current)
+		}
+	}
+}
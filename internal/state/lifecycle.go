@@ -0,0 +1,83 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// transitions lists the statuses each cluster status may move to. The main
+// path is pending → creating → ready → stopping → stopped → deleting →
+// deleted, and a stopped cluster returns to ready when started; an idle
+// ready cluster is paused until it is used again. failed and degraded can
+// be entered from most statuses, and lost and unmanaged are set by the
+// reconciler.
+var transitions = map[string][]string{
+	StatusPending:   {StatusCreating, StatusFailed, StatusDeleting},
+	StatusCreating:  {StatusReady, StatusDegraded, StatusFailed},
+	StatusReady:     {StatusStopping, StatusPaused, StatusDeleting, StatusDegraded, StatusFailed, StatusLost},
+	StatusStopping:  {StatusStopped, StatusDegraded, StatusFailed},
+	StatusStopped:   {StatusReady, StatusDeleting, StatusDegraded, StatusFailed, StatusLost},
+	StatusPaused:    {StatusReady, StatusDeleting, StatusFailed, StatusLost},
+	StatusDeleting:  {StatusDeleted, StatusFailed},
+	StatusDeleted:   {},
+	StatusFailed:    {StatusDeleting, StatusLost},
+	StatusDegraded:  {StatusReady, StatusStopping, StatusDeleting, StatusFailed, StatusLost},
+	StatusLost:      {StatusReady, StatusDeleting},
+	StatusUnmanaged: {StatusReady, StatusDeleting, StatusLost},
+}
+
+// ValidStatus reports whether status is a known cluster status
+func ValidStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// CanTransition reports whether a cluster may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transitional reports whether status is held only while an operation runs
+func Transitional(status string) bool {
+	switch status {
+	case StatusPending, StatusCreating, StatusStopping, StatusDeleting:
+		return true
+	}
+	return false
+}
+
+// Usable reports whether a cluster in status can serve kubeconfigs and
+// accept images
+func Usable(status string) bool {
+	return status == StatusReady || status == StatusDegraded || status == StatusUnmanaged
+}
+
+// TransitionError is returned for a status change the state machine forbids
+type TransitionError struct {
+	Cluster string
+	From    string
+	To      string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cluster %s cannot go from %s to %s", e.Cluster, e.From, e.To)
+}
+
+// Transition moves info to status to, recording reason and the time of the
+// change. It fails with a *TransitionError if the change is not allowed.
+func (info *ClusterInfo) Transition(name, to, reason string) error {
+	if !CanTransition(info.Status, to) {
+		return &TransitionError{Cluster: name, From: info.Status, To: to}
+	}
+
+	now := time.Now()
+	info.Status = to
+	info.StatusReason = reason
+	info.LastTransition = &now
+	return nil
+}
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		// The main path
+		{StatusPending, StatusCreating, true},
+		{StatusCreating, StatusReady, true},
+		{StatusReady, StatusStopping, true},
+		{StatusStopping, StatusStopped, true},
+		{StatusStopped, StatusReady, true},
+		{StatusStopped, StatusDeleting, true},
+		{StatusDeleting, StatusDeleted, true},
+
+		// Idle clusters
+		{StatusReady, StatusPaused, true},
+		{StatusPaused, StatusReady, true},
+		{StatusPaused, StatusDeleting, true},
+		{StatusStopped, StatusPaused, false},
+
+		// Failures and the reconciler
+		{StatusPending, StatusFailed, true},
+		{StatusCreating, StatusFailed, true},
+		{StatusFailed, StatusDeleting, true},
+		{StatusFailed, StatusReady, false},
+		{StatusDegraded, StatusReady, true},
+		{StatusReady, StatusLost, true},
+		{StatusLost, StatusReady, true},
+		{StatusUnmanaged, StatusReady, true},
+		{StatusCreating, StatusLost, false},
+
+		// Skipping steps
+		{StatusPending, StatusReady, false},
+		{StatusReady, StatusStopped, false},
+		{StatusReady, StatusDeleted, false},
+		{StatusStopping, StatusReady, false},
+		{StatusDeleting, StatusReady, false},
+		{StatusDeleted, StatusReady, false},
+		{StatusDeleted, StatusDeleting, false},
+
+		// Unknown statuses
+		{"running", StatusReady, false},
+		{StatusReady, "running", false},
+		{"", StatusReady, false},
+	}
+	for _, test := range tests {
+		if got := CanTransition(test.from, test.to); got != test.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestTransitionTargetsAreKnown(t *testing.T) {
+	for from, targets := range transitions {
+		for _, to := range targets {
+			if !ValidStatus(to) {
+				t.Errorf("%s may move to unknown status %q", from, to)
+			}
+			if to == from {
+				t.Errorf("%s lists itself as a transition", from)
+			}
+		}
+	}
+}
+
+func TestTransition(t *testing.T) {
+	info := ClusterInfo{Status: StatusReady, StatusReason: "old"}
+	if err := info.Transition("dev", StatusStopping, "stop requested"); err != nil {
+		t.Fatal(err)
+	}
+	if info.Status != StatusStopping || info.StatusReason != "stop requested" || info.LastTransition == nil {
+		t.Errorf("after Transition: %+v", info)
+	}
+
+	before := *info.LastTransition
+	err := info.Transition("dev", StatusReady, "")
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.From != StatusStopping || transitionErr.To != StatusReady {
+		t.Fatalf("Transition(stopping → ready) = %v, want a *TransitionError", err)
+	}
+	if info.Status != StatusStopping || !info.LastTransition.Equal(before) {
+		t.Errorf("a refused transition changed the cluster: %+v", info)
+	}
+}
+
+func TestStatusClasses(t *testing.T) {
+	tests := []struct {
+		status       string
+		transitional bool
+		usable       bool
+	}{
+		{StatusPending, true, false},
+		{StatusCreating, true, false},
+		{StatusReady, false, true},
+		{StatusStopping, true, false},
+		{StatusStopped, false, false},
+		{StatusPaused, false, false},
+		{StatusDeleting, true, false},
+		{StatusDeleted, false, false},
+		{StatusFailed, false, false},
+		{StatusDegraded, false, true},
+		{StatusLost, false, false},
+		{StatusUnmanaged, false, true},
+	}
+	for _, test := range tests {
+		if got := Transitional(test.status); got != test.transitional {
+			t.Errorf("Transitional(%s) = %v, want %v", test.status, got, test.transitional)
+		}
+		if got := Usable(test.status); got != test.usable {
+			t.Errorf("Usable(%s) = %v, want %v", test.status, got, test.usable)
+		}
+	}
+	if len(tests) != len(transitions) {
+		t.Errorf("table covers %d statuses; transitions has %d", len(tests), len(transitions))
+	}
+}
@@ -2,43 +2,83 @@ package state
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"time"
 )
 
 const StateFilePath = "/etc/host-manager-state.json"
 
-// Manager handles persistence of host state
+// Manager handles persistence of host state on top of a Store
 type Manager struct {
-	statePath string
+	store Store
+	ports PortRanges
 }
 
-// NewManager creates a new state manager
+// NewManager creates a state manager backed by the JSON state file
 func NewManager() *Manager {
-	return &Manager{
-		statePath: StateFilePath,
-	}
+	return NewManagerWithStore(NewFileStore(StateFilePath))
+}
+
+// NewManagerWithStore creates a state manager backed by store
+func NewManagerWithStore(store Store) *Manager {
+	return &Manager{store: store, ports: DefaultPortRanges}
 }
 
-// Load reads the current host state from disk
+// SetPortRanges sets the host port ranges clusters are allocated ports from
+func (m *Manager) SetPortRanges(ranges PortRanges) {
+	m.ports = ranges
+}
+
+// Close releases the underlying store
+func (m *Manager) Close() error {
+	return m.store.Close()
+}
+
+// Load reads the current host state. Documents written with an older schema
+// are migrated in memory; they are rewritten on the next update.
 func (m *Manager) Load() (*HostState, error) {
-	data, err := ioutil.ReadFile(m.statePath)
+	data, err := m.store.Read()
+	if err != nil {
+		return nil, err
+	}
+	state, _, err := decode(data)
+	return state, err
+}
+
+// decode parses and migrates a stored document, also returning the schema
+// version it was stored with. A nil document yields fresh state.
+func decode(data []byte) (*HostState, int, error) {
+	if data == nil {
+		return &HostState{
+			SchemaVersion: CurrentSchemaVersion,
+			Initialized:   false,
+			Clusters:      make(map[string]ClusterInfo),
+		}, CurrentSchemaVersion, nil
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	version, err := schemaVersion(doc)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Return fresh state if file doesn't exist
-			return &HostState{
-				Initialized: false,
-				Clusters:    make(map[string]ClusterInfo),
-			}, nil
+		return nil, 0, err
+	}
+
+	if version != CurrentSchemaVersion {
+		if err := migrate(doc, version); err != nil {
+			return nil, version, err
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, version, fmt.Errorf("failed to encode migrated state: %w", err)
 		}
-		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
 	var state HostState
 	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to parse state file: %w", err)
+		return nil, version, fmt.Errorf("failed to parse state: %w", err)
 	}
 
 	// Initialize clusters map if nil
@@ -46,88 +86,293 @@ func (m *Manager) Load() (*HostState, error) {
 		state.Clusters = make(map[string]ClusterInfo)
 	}
 
-	return &state, nil
+	return &state, version, nil
 }
 
-// Save writes the host state to disk
-func (m *Manager) Save(state *HostState) error {
-	data, err := json.MarshalIndent(state, "", "  ")
+// Migrate upgrades the stored state to the current schema, keeping a backup
+// of the original. It returns the version found in the store.
+func (m *Manager) Migrate() (int, error) {
+	var from int
+	err := m.transact(func(state *HostState, storedVersion int) error {
+		from = storedVersion
+		if storedVersion == CurrentSchemaVersion {
+			return errNoChange
+		}
+		return nil
+	})
+	if err == errNoChange {
+		err = nil
+	}
+	return from, err
+}
+
+// Import copies the state held by src into the manager's store, migrating it
+// to the current schema. It refuses to overwrite existing state.
+func (m *Manager) Import(src Store) (*HostState, error) {
+	data, err := src.Read()
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("no state to import")
+	}
+
+	imported, _, err := decode(data)
 	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
+		return nil, err
 	}
+	imported.SchemaVersion = CurrentSchemaVersion
 
-	if err := ioutil.WriteFile(m.statePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+	encoded, err := json.MarshalIndent(imported, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	err = m.store.Update(func(tx Tx) error {
+		existing, err := tx.Read()
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("destination already holds state; refusing to overwrite it")
+		}
+		return tx.Write(encoded)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return imported, nil
+}
+
+// Save replaces the stored host state with state. Prefer Update, which
+// cannot lose concurrent modifications.
+func (m *Manager) Save(state *HostState) error {
+	return m.Update(func(current *HostState) error {
+		*current = *state
+		return nil
+	})
+}
+
+// Update applies fn to the current host state and persists the result as a
+// single transaction. If fn returns an error nothing is written.
+func (m *Manager) Update(fn func(*HostState) error) error {
+	return m.transact(func(state *HostState, _ int) error {
+		return fn(state)
+	})
+}
+
+// errNoChange aborts a transaction without writing
+var errNoChange = errors.New("no change")
+
+// transact runs fn inside a store transaction with the current state and the
+// schema version it was stored with, then writes the result
+func (m *Manager) transact(fn func(state *HostState, storedVersion int) error) error {
+	return m.store.Update(func(tx Tx) error {
+		data, err := tx.Read()
+		if err != nil {
+			return err
+		}
+
+		state, version, err := decode(data)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		if err := fn(state, version); err != nil {
+			return err
+		}
+
+		// Keep the pre-migration document before overwriting it with the new schema
+		if version < CurrentSchemaVersion {
+			if err := tx.Backup(fmt.Sprintf("v%d", version)); err != nil {
+				return fmt.Errorf("failed to back up state before migration: %w", err)
+			}
+		}
+
+		state.SchemaVersion = CurrentSchemaVersion
+		encoded, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal state: %w", err)
+		}
+		return tx.Write(encoded)
+	})
 }
 
 // MarkInitialized marks the host as initialized
 func (m *Manager) MarkInitialized(instanceType, storageType, storageDevice string) error {
-	state, err := m.Load()
-	if err != nil {
-		return err
-	}
+	return m.Update(func(state *HostState) error {
+		now := time.Now()
+		state.Initialized = true
+		state.InitializedAt = &now
+		state.InstanceType = instanceType
+		state.StorageType = storageType
+		state.StorageDevice = storageDevice
+		state.PackagesInstalled = true
+		return nil
+	})
+}
+
+// ErrClusterExists is returned when adding a cluster that is already recorded
+var ErrClusterExists = errors.New("cluster already exists")
+
+// AddCluster records a new cluster described by info in the pending status.
+// Each of info.Ports is allocated a free host port; the allocations are held
+// until the cluster is removed from state.
+func (m *Manager) AddCluster(name string, info ClusterInfo) error {
+	return m.Update(func(state *HostState) error {
+		if _, exists := state.Clusters[name]; exists {
+			return ErrClusterExists
+		}
 
-	now := time.Now()
-	state.Initialized = true
-	state.InitializedAt = &now
-	state.InstanceType = instanceType
-	state.StorageType = storageType
-	state.StorageDevice = storageDevice
-	state.PackagesInstalled = true
+		ports, err := state.allocatePorts(m.ports, info.Ports)
+		if err != nil {
+			return err
+		}
 
-	return m.Save(state)
+		now := time.Now()
+		info.Status = StatusPending
+		info.StatusReason = ""
+		info.LastTransition = &now
+		info.Created = &now
+		info.Ports = ports
+		state.Clusters[name] = info
+		return nil
+	})
 }
 
-// UpdateCluster updates information about a cluster
-func (m *Manager) UpdateCluster(name, status, clusterType string, kubevirt bool) error {
-	state, err := m.Load()
-	if err != nil {
-		return err
-	}
+// ErrClusterNotFound is returned for clusters missing from state
+var ErrClusterNotFound = errors.New("cluster not found")
 
-	now := time.Now()
-	state.Clusters[name] = ClusterInfo{
-		Status:   status,
-		Created:  &now,
-		Type:     clusterType,
-		KubeVirt: kubevirt,
-	}
+// TransitionCluster moves a cluster to status to, recording reason. Moving
+// to StatusDeleted removes the cluster from state. A forbidden change
+// returns a *TransitionError.
+func (m *Manager) TransitionCluster(name, to, reason string) error {
+	return m.ModifyCluster(name, func(info *ClusterInfo) error {
+		return info.Transition(name, to, reason)
+	})
+}
+
+// ModifyCluster applies fn to an existing cluster's entry and persists the
+// result. A cluster left in StatusDeleted is removed from state.
+func (m *Manager) ModifyCluster(name string, fn func(info *ClusterInfo) error) error {
+	return m.Update(func(state *HostState) error {
+		info, exists := state.Clusters[name]
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrClusterNotFound, name)
+		}
+
+		if err := fn(&info); err != nil {
+			return err
+		}
+
+		if info.Status == StatusDeleted {
+			delete(state.Clusters, name)
+		} else {
+			state.Clusters[name] = info
+		}
+		return nil
+	})
+}
+
+// ErrClusterManaged is returned when adopting a cluster that is already managed
+var ErrClusterManaged = errors.New("cluster is already managed")
+
+// AdoptCluster records an existing cluster as managed and ready. Clusters
+// the reconciler recorded as unmanaged or lost may be adopted; any other
+// existing entry is left untouched and ErrClusterManaged is returned.
+func (m *Manager) AdoptCluster(name string, info ClusterInfo) error {
+	return m.Update(func(state *HostState) error {
+		now := time.Now()
+		if existing, exists := state.Clusters[name]; exists {
+			if existing.Status != StatusUnmanaged && existing.Status != StatusLost {
+				return ErrClusterManaged
+			}
+			if info.Created == nil {
+				info.Created = existing.Created
+			}
+		}
 
-	return m.Save(state)
+		info.Status = StatusReady
+		info.StatusReason = ""
+		info.LastTransition = &now
+		info.Adopted = &now
+		state.Clusters[name] = info
+		return nil
+	})
 }
 
 // RemoveCluster removes a cluster from state
 func (m *Manager) RemoveCluster(name string) error {
-	state, err := m.Load()
-	if err != nil {
-		return err
-	}
-
-	delete(state.Clusters, name)
-	return m.Save(state)
+	return m.Update(func(state *HostState) error {
+		delete(state.Clusters, name)
+		return nil
+	})
 }
 
 // SetRegistryStatus updates the registry status
 func (m *Manager) SetRegistryStatus(running bool) error {
-	state, err := m.Load()
-	if err != nil {
-		return err
-	}
-
-	state.RegistryRunning = running
-	return m.Save(state)
+	return m.Update(func(state *HostState) error {
+		state.RegistryRunning = running
+		return nil
+	})
 }
 
 // SetBaseClusterReady marks the base cluster as ready
 func (m *Manager) SetBaseClusterReady() error {
-	state, err := m.Load()
+	return m.Update(func(state *HostState) error {
+		state.BaseClusterReady = true
+		return nil
+	})
+}
+
+// MarkInterrupted fails clusters left in a transitional status because the
+// operation driving them stopped, and returns their names. With no names,
+// every transitional cluster is considered, as after a crash.
+func (m *Manager) MarkInterrupted(names ...string) ([]string, error) {
+	selected := func(name string, info ClusterInfo) bool {
+		if !Transitional(info.Status) {
+			return false
+		}
+		if len(names) == 0 {
+			return true
+		}
+		for _, n := range names {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Avoid writing state when there is nothing to do
+	current, err := m.Load()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	pending := false
+	for name, info := range current.Clusters {
+		if selected(name, info) {
+			pending = true
+		}
+	}
+	if !pending {
+		return nil, nil
 	}
 
-	state.BaseClusterReady = true
-	return m.Save(state)
-}
\ No newline at end of file
+	var interrupted []string
+	err = m.Update(func(state *HostState) error {
+		interrupted = nil
+		for name, info := range state.Clusters {
+			if !selected(name, info) {
+				continue
+			}
+			if err := info.Transition(name, StatusFailed, "interrupted while "+info.Status); err != nil {
+				return err
+			}
+			state.Clusters[name] = info
+			interrupted = append(interrupted, name)
+		}
+		return nil
+	})
+	return interrupted, err
+}
@@ -0,0 +1,96 @@
+package state
+
+import "fmt"
+
+// CurrentSchemaVersion is the state file schema written by this build
+const CurrentSchemaVersion = 2
+
+// migration upgrades a raw decoded state document from one schema version
+// to the next
+type migration struct {
+	description string
+	apply       func(doc map[string]interface{}) error
+}
+
+// migrations holds the upgrade step from version i to i+1 at index i.
+// Append a step here whenever CurrentSchemaVersion is bumped.
+var migrations = []migration{
+	{
+		description: "add schema_version and default cluster types",
+		apply:       migrateV0ToV1,
+	},
+	{
+		description: "map cluster statuses onto the lifecycle state machine",
+		apply:       migrateV1ToV2,
+	},
+}
+
+// migrate upgrades doc in place from version from to CurrentSchemaVersion
+func migrate(doc map[string]interface{}, from int) error {
+	if from > CurrentSchemaVersion {
+		return fmt.Errorf("state file schema version %d is newer than the supported version %d; upgrade host-manager instead of downgrading", from, CurrentSchemaVersion)
+	}
+
+	for v := from; v < CurrentSchemaVersion; v++ {
+		if err := migrations[v].apply(doc); err != nil {
+			return fmt.Errorf("failed to migrate state from schema version %d to %d (%s): %w", v, v+1, migrations[v].description, err)
+		}
+		doc["schema_version"] = v + 1
+	}
+
+	return nil
+}
+
+// schemaVersion returns the schema version recorded in doc. Files written
+// before versioning was introduced have no version and are treated as 0.
+func schemaVersion(doc map[string]interface{}) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok {
+		return 0, nil
+	}
+
+	version, ok := raw.(float64)
+	if !ok || version < 0 || version != float64(int(version)) {
+		return 0, fmt.Errorf("invalid schema_version %v in state file", raw)
+	}
+	return int(version), nil
+}
+
+// migrateV0ToV1 fills in the cluster type that early versions did not always record
+func migrateV0ToV1(doc map[string]interface{}) error {
+	clusters, _ := doc["clusters"].(map[string]interface{})
+	for name, raw := range clusters {
+		info, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cluster %s has unexpected format", name)
+		}
+		if t, _ := info["type"].(string); t == "" {
+			if name == "kind" {
+				info["type"] = "infrastructure"
+			} else {
+				info["type"] = "development"
+			}
+		}
+	}
+	return nil
+}
+
+// migrateV1ToV2 renames statuses that the lifecycle state machine replaced:
+// "running" became "ready" and "interrupted" became "failed" with a reason
+func migrateV1ToV2(doc map[string]interface{}) error {
+	clusters, _ := doc["clusters"].(map[string]interface{})
+	for name, raw := range clusters {
+		info, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cluster %s has unexpected format", name)
+		}
+		switch info["status"] {
+		case "running":
+			info["status"] = StatusReady
+		case "interrupted":
+			info["status"] = StatusFailed
+			info["status_reason"] = "interrupted by a restart"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// decodeDoc parses a state document the way the stores hand it to migrate
+func decodeDoc(t *testing.T, doc string) map[string]interface{} {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestMigrateV1ToV2(t *testing.T) {
+	tests := []struct {
+		cluster    string // the v1 cluster record
+		wantStatus string
+		wantReason string
+	}{
+		{`{"status":"running","type":"development"}`, StatusReady, ""},
+		{`{"status":"interrupted","type":"development"}`, StatusFailed, "interrupted by a restart"},
+		{`{"status":"stopped","type":"development"}`, StatusStopped, ""},
+		{`{"status":"failed","status_reason":"boom","type":"development"}`, StatusFailed, "boom"},
+		{`{"status":"creating","type":"development"}`, StatusCreating, ""},
+	}
+	for _, test := range tests {
+		doc := decodeDoc(t, `{"clusters":{"dev":`+test.cluster+`}}`)
+		if err := migrateV1ToV2(doc); err != nil {
+			t.Errorf("migrateV1ToV2(%s) = %v", test.cluster, err)
+			continue
+		}
+		info := doc["clusters"].(map[string]interface{})["dev"].(map[string]interface{})
+		reason, _ := info["status_reason"].(string)
+		if info["status"] != test.wantStatus || reason != test.wantReason {
+			t.Errorf("migrateV1ToV2(%s) gave status %v reason %q, want %s %q", test.cluster, info["status"], reason, test.wantStatus, test.wantReason)
+		}
+	}
+
+	if err := migrateV1ToV2(decodeDoc(t, `{"clusters":{"dev":"running"}}`)); err == nil {
+		t.Error("migrateV1ToV2 accepted a malformed cluster record")
+	}
+	if err := migrateV1ToV2(decodeDoc(t, `{"initialized":true}`)); err != nil {
+		t.Errorf("migrateV1ToV2 without clusters = %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	doc := decodeDoc(t, `{"initialized":true,"clusters":{"kind":{"status":"running"},"dev":{"status":"interrupted"}}}`)
+	version, err := schemaVersion(doc)
+	if err != nil || version != 0 {
+		t.Fatalf("schemaVersion() = %d, %v; want 0 for an unversioned file", version, err)
+	}
+	if err := migrate(doc, version); err != nil {
+		t.Fatal(err)
+	}
+
+	var migrated HostState
+	data, _ := json.Marshal(doc)
+	if err := json.Unmarshal(data, &migrated); err != nil {
+		t.Fatal(err)
+	}
+	if migrated.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("schema_version %d, want %d", migrated.SchemaVersion, CurrentSchemaVersion)
+	}
+	if kind := migrated.Clusters["kind"]; kind.Type != "infrastructure" || kind.Status != StatusReady {
+		t.Errorf("kind cluster migrated to %+v", kind)
+	}
+	if dev := migrated.Clusters["dev"]; dev.Type != "development" || dev.Status != StatusFailed {
+		t.Errorf("dev cluster migrated to %+v", dev)
+	}
+
+	if err := migrate(decodeDoc(t, `{}`), CurrentSchemaVersion+1); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("migrate from a newer schema = %v, want an error", err)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	tests := []struct {
+		doc     string
+		want    int
+		wantErr bool
+	}{
+		{`{}`, 0, false},
+		{`{"schema_version":1}`, 1, false},
+		{`{"schema_version":2}`, 2, false},
+		{`{"schema_version":1.5}`, 0, true},
+		{`{"schema_version":-1}`, 0, true},
+		{`{"schema_version":"2"}`, 0, true},
+	}
+	for _, test := range tests {
+		got, err := schemaVersion(decodeDoc(t, test.doc))
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("schemaVersion(%s) = %d, %v", test.doc, got, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// LocalMount is the mount every cluster gets, at /local in each node
+var LocalMount = Mount{Name: "local", ContainerPath: "/local"}
+
+var mountName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// reservedContainerPaths hold the node's own system files; mounting over
+// them would break the node
+var reservedContainerPaths = []string{"/etc", "/usr", "/var", "/proc", "/sys", "/dev", "/run", "/kind", "/bin", "/sbin", "/lib", "/boot"}
+
+// NormalizeMounts validates the extra mounts of a create request and returns
+// every mount of the cluster, LocalMount first
+func NormalizeMounts(mounts []Mount) ([]Mount, error) {
+	normalized := []Mount{LocalMount}
+	names := map[string]bool{LocalMount.Name: true}
+	paths := map[string]bool{LocalMount.ContainerPath: true}
+
+	for _, mount := range mounts {
+		if !mountName.MatchString(mount.Name) {
+			return nil, fmt.Errorf("invalid mount name %q: use lowercase letters, digits, '.', '_' and '-'", mount.Name)
+		}
+		if names[mount.Name] {
+			return nil, fmt.Errorf("mount name %q is used twice or reserved", mount.Name)
+		}
+
+		containerPath := path.Clean(mount.ContainerPath)
+		if !path.IsAbs(mount.ContainerPath) || containerPath == "/" {
+			return nil, fmt.Errorf("mount %s needs an absolute container_path below /", mount.Name)
+		}
+		for _, reserved := range reservedContainerPaths {
+			if containerPath == reserved || strings.HasPrefix(containerPath, reserved+"/") {
+				return nil, fmt.Errorf("mount %s cannot use container_path %s", mount.Name, containerPath)
+			}
+		}
+		if paths[containerPath] {
+			return nil, fmt.Errorf("container_path %s is mounted twice", containerPath)
+		}
+
+		names[mount.Name] = true
+		paths[containerPath] = true
+		normalized = append(normalized, Mount{Name: mount.Name, ContainerPath: containerPath})
+	}
+	return normalized, nil
+}
+
+// ValidDataPolicy reports whether policy is a known data policy; empty
+// means the default, DataDelete
+func ValidDataPolicy(policy string) bool {
+	return policy == "" || policy == DataDelete || policy == DataRetain
+}
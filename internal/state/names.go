@@ -0,0 +1,22 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// clusterName is the DNS label kind requires of cluster names
+var clusterName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ValidateClusterName checks that a cluster name is a DNS label of at most
+// 63 characters, as kind requires. Names are also used as host directory
+// and container names, so nothing else is accepted.
+func ValidateClusterName(name string) error {
+	if name == "" {
+		return fmt.Errorf("cluster name is required")
+	}
+	if len(name) > 63 || !clusterName.MatchString(name) {
+		return fmt.Errorf("invalid cluster name %q: use at most 63 lowercase letters, digits and '-', starting and ending with a letter or digit", name)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateClusterName(t *testing.T) {
+	valid := []string{"kind", "dev", "dev-1", "a", "0abc", strings.Repeat("a", 63)}
+	for _, name := range valid {
+		if err := ValidateClusterName(name); err != nil {
+			t.Errorf("ValidateClusterName(%q) = %v", name, err)
+		}
+	}
+
+	invalid := []string{"", "..", ".", "a/b", "/root", "/etc/passwd", "../x", "Dev", "-dev", "dev-", "dev_1", "dev.1", strings.Repeat("a", 64)}
+	for _, name := range invalid {
+		if err := ValidateClusterName(name); err == nil {
+			t.Errorf("ValidateClusterName(%q) succeeded", name)
+		}
+	}
+}
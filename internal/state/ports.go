@@ -0,0 +1,154 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SSHContainerPort is the node port the SSH mapping of a cluster forwards to
+const SSHContainerPort = 32222
+
+// APIServerContainerPort is the port the API server listens on in the
+// control-plane node
+const APIServerContainerPort = 6443
+
+// Kubernetes' default NodePort service range
+const (
+	MinNodePort = 30000
+	MaxNodePort = 32767
+)
+
+// PortRange is an inclusive range of host ports
+type PortRange struct {
+	First int
+	Last  int
+}
+
+// ParsePortRange parses a range such as "2222-2321"
+func ParsePortRange(s string) (PortRange, error) {
+	first, last, ok := strings.Cut(s, "-")
+	if !ok {
+		return PortRange{}, fmt.Errorf("invalid port range %q: expected FIRST-LAST", s)
+	}
+
+	var r PortRange
+	var err error
+	if r.First, err = strconv.Atoi(strings.TrimSpace(first)); err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	if r.Last, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	if r.First < 1 || r.Last > 65535 || r.First > r.Last {
+		return PortRange{}, fmt.Errorf("invalid port range %q: ports must be 1-65535 and FIRST <= LAST", s)
+	}
+	return r, nil
+}
+
+func (r PortRange) String() string {
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// PortRanges are the host port ranges each kind of mapping is allocated from
+type PortRanges struct {
+	APIServer PortRange
+	SSH       PortRange
+	NodePort  PortRange
+}
+
+// DefaultPortRanges leave room for 100 clusters. The SSH range starts at the
+// port the base cluster has always used.
+var DefaultPortRanges = PortRanges{
+	APIServer: PortRange{First: 6443, Last: 6542},
+	SSH:       PortRange{First: 2222, Last: 2321},
+	NodePort:  PortRange{First: 40000, Last: 40999},
+}
+
+// rangeFor returns the range mappings for purpose are allocated from
+func (r PortRanges) rangeFor(purpose string) (PortRange, error) {
+	switch purpose {
+	case PortAPIServer:
+		return r.APIServer, nil
+	case PortSSH:
+		return r.SSH, nil
+	case PortNodePort, PortIngress:
+		return r.NodePort, nil
+	}
+	return PortRange{}, fmt.Errorf("unknown port purpose %q", purpose)
+}
+
+// ErrPortsExhausted is returned when a range has no free port left
+var ErrPortsExhausted = errors.New("no free host port")
+
+// ClusterPorts returns the mappings every cluster gets plus one for each
+// requested NodePort service port
+func ClusterPorts(nodePorts []int) ([]PortMapping, error) {
+	ports := []PortMapping{
+		{Purpose: PortAPIServer, ContainerPort: APIServerContainerPort},
+		{Purpose: PortSSH, ContainerPort: SSHContainerPort},
+	}
+
+	seen := make(map[int]bool)
+	for _, port := range nodePorts {
+		if port < MinNodePort || port > MaxNodePort {
+			return nil, fmt.Errorf("node port %d is outside the NodePort range %d-%d", port, MinNodePort, MaxNodePort)
+		}
+		if port == SSHContainerPort {
+			return nil, fmt.Errorf("node port %d is reserved for SSH", port)
+		}
+		if seen[port] {
+			return nil, fmt.Errorf("node port %d requested twice", port)
+		}
+		seen[port] = true
+		ports = append(ports, PortMapping{Purpose: PortNodePort, ContainerPort: port})
+	}
+	return ports, nil
+}
+
+// allocatePorts assigns a free host port to each mapping, skipping ports
+// held by any cluster in state and ports already bound on the host
+func (s *HostState) allocatePorts(ranges PortRanges, ports []PortMapping) ([]PortMapping, error) {
+	used := make(map[int]bool)
+	for _, info := range s.Clusters {
+		for _, mapping := range info.Ports {
+			used[mapping.HostPort] = true
+		}
+	}
+
+	allocated := make([]PortMapping, 0, len(ports))
+	for _, mapping := range ports {
+		r, err := ranges.rangeFor(mapping.Purpose)
+		if err != nil {
+			return nil, err
+		}
+
+		mapping.HostPort = 0
+		for port := r.First; port <= r.Last; port++ {
+			if !used[port] && portAvailable(port) {
+				mapping.HostPort = port
+				break
+			}
+		}
+		if mapping.HostPort == 0 {
+			return nil, fmt.Errorf("%w for %s in %s", ErrPortsExhausted, mapping.Purpose, r)
+		}
+
+		used[mapping.HostPort] = true
+		allocated = append(allocated, mapping)
+	}
+	return allocated, nil
+}
+
+// portAvailable reports whether nothing on the host listens on port, which
+// catches clusters created outside host-manager and unrelated services
+func portAvailable(port int) bool {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
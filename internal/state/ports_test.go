@@ -0,0 +1,111 @@
+package state
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    PortRange
+		wantErr bool
+	}{
+		{"2222-2321", PortRange{2222, 2321}, false},
+		{" 40000 - 40999 ", PortRange{40000, 40999}, false},
+		{"6443-6443", PortRange{6443, 6443}, false},
+		{"1-65535", PortRange{1, 65535}, false},
+		{"2222", PortRange{}, true},
+		{"a-b", PortRange{}, true},
+		{"0-10", PortRange{}, true},
+		{"100-65536", PortRange{}, true},
+		{"2321-2222", PortRange{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParsePortRange(test.in)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParsePortRange(%q) = %v, %v", test.in, got, err)
+		}
+	}
+}
+
+func TestClusterPorts(t *testing.T) {
+	tests := []struct {
+		nodePorts []int
+		want      int    // mappings returned
+		err       string // empty when the request is valid
+	}{
+		{nil, 2, ""},
+		{[]int{30080, 30443}, 4, ""},
+		{[]int{MinNodePort, MaxNodePort}, 4, ""},
+		{[]int{8080}, 0, "outside the NodePort range"},
+		{[]int{MaxNodePort + 1}, 0, "outside the NodePort range"},
+		{[]int{SSHContainerPort}, 0, "reserved for SSH"},
+		{[]int{30080, 30080}, 0, "requested twice"},
+	}
+	for _, test := range tests {
+		ports, err := ClusterPorts(test.nodePorts)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ClusterPorts(%v) = %v, want an error containing %q", test.nodePorts, err, test.err)
+			}
+			continue
+		}
+		if err != nil || len(ports) != test.want {
+			t.Errorf("ClusterPorts(%v) = %v, %v; want %d mappings", test.nodePorts, ports, err, test.want)
+		}
+	}
+}
+
+func TestAllocatePorts(t *testing.T) {
+	// A port bound on the host by something else
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	bound := listener.Addr().(*net.TCPAddr).Port
+	if bound+3 > 65535 {
+		t.Skip("no room above the bound port")
+	}
+
+	ranges := PortRanges{
+		APIServer: PortRange{First: bound, Last: bound + 2},
+		SSH:       PortRange{First: bound, Last: bound + 2},
+		NodePort:  PortRange{First: bound, Last: bound + 2},
+	}
+	state := &HostState{Clusters: map[string]ClusterInfo{
+		"dev": {Ports: []PortMapping{{Purpose: PortAPIServer, ContainerPort: APIServerContainerPort, HostPort: bound + 1}}},
+	}}
+
+	tests := []struct {
+		ports []PortMapping
+		want  []int  // host ports allocated
+		err   string // empty when allocation succeeds
+	}{
+		// Skips the bound port and the one held by dev
+		{[]PortMapping{{Purpose: PortAPIServer}}, []int{bound + 2}, ""},
+		// Mappings of one request do not share a port
+		{[]PortMapping{{Purpose: PortSSH}, {Purpose: PortNodePort}}, nil, ErrPortsExhausted.Error()},
+		{[]PortMapping{{Purpose: "bogus"}}, nil, "unknown port purpose"},
+	}
+	for _, test := range tests {
+		allocated, err := state.allocatePorts(ranges, test.ports)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("allocatePorts(%v) = %v, want an error containing %q", test.ports, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("allocatePorts(%v) = %v", test.ports, err)
+			continue
+		}
+		for i, mapping := range allocated {
+			if mapping.HostPort != test.want[i] {
+				t.Errorf("allocatePorts(%v) gave host port %d, want %d", test.ports, mapping.HostPort, test.want[i])
+			}
+		}
+	}
+}
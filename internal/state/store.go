@@ -0,0 +1,47 @@
+package state
+
+import "fmt"
+
+// Backend names accepted by OpenStore
+const (
+	BackendJSON = "json"
+	BackendBolt = "bolt"
+)
+
+// Store persists the encoded host state document. Implementations must make
+// Update atomic: either everything written inside fn is stored or nothing is.
+type Store interface {
+	// Read returns the stored document, or nil if nothing has been stored yet
+	Read() ([]byte, error)
+
+	// Update runs fn with exclusive access to the document
+	Update(fn func(tx Tx) error) error
+
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// Tx is the view of a Store inside Update
+type Tx interface {
+	// Read returns the stored document, or nil if nothing has been stored yet
+	Read() ([]byte, error)
+
+	// Write replaces the stored document
+	Write(data []byte) error
+
+	// Backup keeps a copy of the document as currently stored under name.
+	// An existing backup with the same name is left untouched.
+	Backup(name string) error
+}
+
+// OpenStore opens the store for backend at path
+func OpenStore(backend, path string) (Store, error) {
+	switch backend {
+	case BackendJSON:
+		return NewFileStore(path), nil
+	case BackendBolt:
+		return OpenBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown state backend %q (expected %s or %s)", backend, BackendJSON, BackendBolt)
+	}
+}
@@ -0,0 +1,190 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const DatabasePath = "/etc/host-manager-state.db"
+
+var (
+	stateBucket   = []byte("state")
+	clusterBucket = []byte("clusters")
+	backupBucket  = []byte("state-backups")
+	hostStateKey  = []byte("host")
+)
+
+// clustersField is the member of the state document holding the clusters
+const clustersField = "clusters"
+
+// BoltStore keeps the state document in an embedded bbolt database. Each
+// cluster is stored under its name in the clusters bucket and the rest of the
+// document under the host key, so an update only rewrites the clusters that
+// changed. A host document that still holds its clusters was written before
+// they were split out and is read as is until the next update. The database
+// file is locked by the process that opens it, so only one server can use it
+// at a time.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates the database at path
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{stateBucket, clusterBucket, backupBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize state database: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Read implements Store
+func (s *BoltStore) Read() ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		data, err = readDocument(tx)
+		return err
+	})
+	return data, err
+}
+
+// Update implements Store
+func (s *BoltStore) Update(fn func(tx Tx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(&boltTx{tx: tx})
+	})
+}
+
+// Close implements Store
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (t *boltTx) Read() ([]byte, error) {
+	return readDocument(t.tx)
+}
+
+func (t *boltTx) Write(data []byte) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to split state document: %w", err)
+	}
+	var clusters map[string]json.RawMessage
+	if raw, ok := doc[clustersField]; ok {
+		if err := json.Unmarshal(raw, &clusters); err != nil {
+			return fmt.Errorf("failed to split state document: %w", err)
+		}
+		delete(doc, clustersField)
+	}
+
+	bucket := t.tx.Bucket(clusterBucket)
+	var removed [][]byte
+	err := bucket.ForEach(func(name, _ []byte) error {
+		if _, ok := clusters[string(name)]; !ok {
+			removed = append(removed, copyBytes(name))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range removed {
+		if err := bucket.Delete(name); err != nil {
+			return err
+		}
+	}
+	for name, raw := range clusters {
+		var value bytes.Buffer
+		if err := json.Compact(&value, raw); err != nil {
+			return err
+		}
+		if bytes.Equal(bucket.Get([]byte(name)), value.Bytes()) {
+			continue
+		}
+		if err := bucket.Put([]byte(name), value.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	host, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return t.tx.Bucket(stateBucket).Put(hostStateKey, host)
+}
+
+func (t *boltTx) Backup(name string) error {
+	backups := t.tx.Bucket(backupBucket)
+	if backups.Get([]byte(name)) != nil {
+		return nil
+	}
+
+	data, err := readDocument(t.tx)
+	if err != nil || data == nil {
+		return err
+	}
+	return backups.Put([]byte(name), data)
+}
+
+// readDocument assembles the state document from the host key and the
+// clusters bucket. It returns nil if nothing has been stored yet.
+func readDocument(tx *bolt.Tx) ([]byte, error) {
+	host := tx.Bucket(stateBucket).Get(hostStateKey)
+	if host == nil {
+		return nil, nil
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(host, &doc); err != nil {
+		return nil, fmt.Errorf("failed to read state document: %w", err)
+	}
+	if _, ok := doc[clustersField]; ok {
+		return copyBytes(host), nil
+	}
+
+	clusters := make(map[string]json.RawMessage)
+	err := tx.Bucket(clusterBucket).ForEach(func(name, value []byte) error {
+		clusters[string(name)] = copyBytes(value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(clusters)
+	if err != nil {
+		return nil, err
+	}
+	doc[clustersField] = raw
+	return json.Marshal(doc)
+}
+
+// copyBytes copies a value out of bbolt, whose slices are only valid for the
+// life of the transaction
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltStoreKeepsClustersByName(t *testing.T) {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	m := NewManagerWithStore(store)
+
+	err = m.Update(func(state *HostState) error {
+		state.Clusters["dev"] = ClusterInfo{Status: StatusReady, Type: "development"}
+		state.Clusters["test"] = ClusterInfo{Status: StatusStopped, Type: "development"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveCluster("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var hostDoc []byte
+	store.db.View(func(tx *bolt.Tx) error {
+		tx.Bucket(clusterBucket).ForEach(func(name, _ []byte) error {
+			names = append(names, string(name))
+			return nil
+		})
+		hostDoc = copyBytes(tx.Bucket(stateBucket).Get(hostStateKey))
+		return nil
+	})
+	if len(names) != 1 || names[0] != "dev" {
+		t.Errorf("clusters bucket holds %v, want [dev]", names)
+	}
+	if doc := string(hostDoc); doc == "" || strings.Contains(doc, `"clusters"`) {
+		t.Errorf("host document %s should not hold the clusters", doc)
+	}
+
+	loaded, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Clusters) != 1 || loaded.Clusters["dev"].Status != StatusReady {
+		t.Errorf("loaded clusters %+v, want dev only", loaded.Clusters)
+	}
+}
+
+func TestBoltStoreReadsUnsplitDocument(t *testing.T) {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	legacy := `{"schema_version":` + strconv.Itoa(CurrentSchemaVersion) + `,"initialized":true,"clusters":{"old":{"status":"ready","type":"development"}}}`
+	store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Put(hostStateKey, []byte(legacy))
+	})
+	m := NewManagerWithStore(store)
+
+	loaded, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Clusters["old"]; !ok || !loaded.Initialized {
+		t.Fatalf("unsplit document not read: %+v", loaded)
+	}
+
+	// The next update moves the clusters into their bucket
+	if err := m.Update(func(*HostState) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	var stored []byte
+	store.db.View(func(tx *bolt.Tx) error {
+		stored = copyBytes(tx.Bucket(clusterBucket).Get([]byte("old")))
+		return nil
+	})
+	if stored == nil {
+		t.Error("cluster old was not moved into the clusters bucket")
+	}
+	if loaded, err = m.Load(); err != nil || len(loaded.Clusters) != 1 {
+		t.Errorf("after update loaded %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,159 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// FileStore keeps the state document in a single JSON file. Updates are
+// serialized within the process by a mutex and across processes by an
+// advisory lock on a sibling lock file, and the file is replaced atomically
+// so readers never observe a partial write.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a store backed by the file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Read implements Store
+func (s *FileStore) Read() ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return data, nil
+}
+
+// Update implements Store
+func (s *FileStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tx := &fileTx{store: s}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if tx.data == nil {
+		return nil
+	}
+	return s.write(tx.data)
+}
+
+// Close implements Store
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// fileTx buffers the document written during an update
+type fileTx struct {
+	store *FileStore
+	data  []byte
+}
+
+func (tx *fileTx) Read() ([]byte, error) {
+	if tx.data != nil {
+		return tx.data, nil
+	}
+	return tx.store.Read()
+}
+
+func (tx *fileTx) Write(data []byte) error {
+	tx.data = data
+	return nil
+}
+
+func (tx *fileTx) Backup(name string) error {
+	dst := fmt.Sprintf("%s.%s.bak", tx.store.path, name)
+	if err := copyFile(tx.store.path, dst); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to back up state file: %w", err)
+	}
+	return nil
+}
+
+// write atomically replaces the state file: the new content is written to a
+// temporary file in the same directory, synced, and renamed over the old one
+func (s *FileStore) write(data []byte) error {
+	dir := filepath.Dir(s.path)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set state file permissions: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// copyFile copies src to dst, leaving an existing dst untouched
+func copyFile(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0600)
+}
+
+// lockFile takes an exclusive advisory lock on path, creating it if needed,
+// and returns a function that releases it. It blocks until the lock is free.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock state file: %w", err)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,85 @@
+package state
+
+import "fmt"
+
+// Topology limits
+const (
+	MaxControlPlanes = 5
+	MaxWorkers       = 10
+)
+
+var taintEffects = map[string]bool{
+	"NoSchedule":       true,
+	"PreferNoSchedule": true,
+	"NoExecute":        true,
+}
+
+// NormalizeTopology validates a requested topology and expands it so Nodes
+// lists every node, control planes first. A nil topology is a single
+// control-plane node.
+func NormalizeTopology(t *Topology) (*Topology, error) {
+	if t == nil {
+		t = &Topology{}
+	}
+
+	controlPlanes := t.ControlPlanes
+	if controlPlanes == 0 {
+		controlPlanes = 1
+	}
+	if controlPlanes < 0 || controlPlanes > MaxControlPlanes || controlPlanes%2 == 0 {
+		return nil, fmt.Errorf("control_planes must be 1, 3 or 5 (etcd needs an odd member count), got %d", t.ControlPlanes)
+	}
+	if t.Workers < 0 || t.Workers > MaxWorkers {
+		return nil, fmt.Errorf("workers must be between 0 and %d, got %d", MaxWorkers, t.Workers)
+	}
+
+	normalized := &Topology{ControlPlanes: controlPlanes, Workers: t.Workers}
+	for i := 0; i < controlPlanes; i++ {
+		normalized.Nodes = append(normalized.Nodes, NodeSpec{Role: RoleControlPlane, Index: i})
+	}
+	for i := 0; i < t.Workers; i++ {
+		normalized.Nodes = append(normalized.Nodes, NodeSpec{Role: RoleWorker, Index: i})
+	}
+
+	for _, spec := range t.Nodes {
+		node := normalized.node(spec.Role, spec.Index)
+		if node == nil {
+			return nil, fmt.Errorf("topology has no %s node %d", spec.Role, spec.Index)
+		}
+		// Labels and taints follow the Kubernetes syntax, which also keeps
+		// them from breaking the kind config they are rendered into
+		for key, value := range spec.Labels {
+			if !validKey(key) {
+				return nil, fmt.Errorf("%s node %d has invalid label key %q", spec.Role, spec.Index, key)
+			}
+			if value != "" && !labelName.MatchString(value) {
+				return nil, fmt.Errorf("%s node %d has invalid value %q for label %s", spec.Role, spec.Index, value, key)
+			}
+		}
+		for _, taint := range spec.Taints {
+			if !validKey(taint.Key) {
+				return nil, fmt.Errorf("%s node %d has invalid taint key %q", spec.Role, spec.Index, taint.Key)
+			}
+			if taint.Value != "" && !labelName.MatchString(taint.Value) {
+				return nil, fmt.Errorf("%s node %d has invalid value %q for taint %s", spec.Role, spec.Index, taint.Value, taint.Key)
+			}
+			if !taintEffects[taint.Effect] {
+				return nil, fmt.Errorf("%s node %d has taint %s with invalid effect %q", spec.Role, spec.Index, taint.Key, taint.Effect)
+			}
+		}
+		node.Labels = spec.Labels
+		node.Taints = spec.Taints
+	}
+
+	return normalized, nil
+}
+
+// node returns the node with the given role and index
+func (t *Topology) node(role string, index int) *NodeSpec {
+	for i := range t.Nodes {
+		if t.Nodes[i].Role == role && t.Nodes[i].Index == index {
+			return &t.Nodes[i]
+		}
+	}
+	return nil
+}
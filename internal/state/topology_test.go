@@ -0,0 +1,72 @@
+package state
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTopology(t *testing.T) {
+	tests := []struct {
+		topology *Topology
+		want     string // the nodes, as role/index
+	}{
+		{nil, "control-plane/0"},
+		{&Topology{Workers: 2}, "control-plane/0 worker/0 worker/1"},
+		{&Topology{ControlPlanes: 3, Workers: 1}, "control-plane/0 control-plane/1 control-plane/2 worker/0"},
+		{&Topology{Workers: 1, Nodes: []NodeSpec{{Role: RoleWorker, Index: 0, Labels: map[string]string{"gpu": "true"}}}}, "control-plane/0 worker/0"},
+	}
+	for _, test := range tests {
+		got, err := NormalizeTopology(test.topology)
+		if err != nil {
+			t.Errorf("NormalizeTopology(%+v) = %v", test.topology, err)
+			continue
+		}
+		var nodes []string
+		for _, node := range got.Nodes {
+			nodes = append(nodes, node.Role+"/"+strconv.Itoa(node.Index))
+		}
+		if strings.Join(nodes, " ") != test.want {
+			t.Errorf("NormalizeTopology(%+v) nodes %v, want %s", test.topology, nodes, test.want)
+		}
+	}
+}
+
+func TestNormalizeTopologyNodes(t *testing.T) {
+	worker := func(labels map[string]string, taints ...Taint) *Topology {
+		return &Topology{Workers: 1, Nodes: []NodeSpec{{Role: RoleWorker, Index: 0, Labels: labels, Taints: taints}}}
+	}
+	tests := []struct {
+		topology *Topology
+		err      string // empty when the topology is valid
+	}{
+		{worker(map[string]string{"gpu": "true", "example.com/zone": "a", "empty": ""}), ""},
+		{worker(nil, Taint{Key: "dedicated", Value: "db", Effect: "NoSchedule"}), ""},
+		{worker(nil, Taint{Key: "node.example.com/maintenance", Effect: "NoExecute"}), ""},
+		{worker(map[string]string{"": "x"}), "invalid label key"},
+		{worker(map[string]string{"bad key": "x"}), "invalid label key"},
+		{worker(map[string]string{"Example.com/zone": "a"}), "invalid label key"},
+		{worker(map[string]string{"zone": "a b"}), "invalid value"},
+		{worker(map[string]string{"zone": "\"\nx: y"}), "invalid value"},
+		{worker(map[string]string{"zone": strings.Repeat("a", 64)}), "invalid value"},
+		{worker(nil, Taint{Effect: "NoSchedule"}), "invalid taint key"},
+		{worker(nil, Taint{Key: "a/b/c", Effect: "NoSchedule"}), "invalid taint key"},
+		{worker(nil, Taint{Key: "dedicated", Value: "db/1", Effect: "NoSchedule"}), "invalid value"},
+		{worker(nil, Taint{Key: "dedicated", Effect: "Never"}), "invalid effect"},
+		{&Topology{ControlPlanes: 2}, "control_planes"},
+		{&Topology{Workers: MaxWorkers + 1}, "workers"},
+		{&Topology{Nodes: []NodeSpec{{Role: RoleWorker, Index: 0}}}, "no worker node 0"},
+	}
+	for _, test := range tests {
+		_, err := NormalizeTopology(test.topology)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("NormalizeTopology(%+v) = %v", test.topology.Nodes, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("NormalizeTopology(%+v) = %v, want an error containing %q", test.topology, err, test.err)
+		}
+	}
+}
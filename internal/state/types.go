@@ -4,23 +4,62 @@ import "time"
 
 // HostState represents the current state of the host system
 type HostState struct {
-	Initialized      bool                   `json:"initialized"`
-	InitializedAt    *time.Time             `json:"initialized_at,omitempty"`
-	InstanceType     string                 `json:"instance_type,omitempty"`
-	StorageType      string                 `json:"storage_type,omitempty"`     // "nvme", "ebs-only"
-	StorageDevice    string                 `json:"storage_device,omitempty"`   // "/dev/nvme1n1"
-	PackagesInstalled bool                  `json:"packages_installed"`
-	BaseClusterReady bool                   `json:"base_cluster_ready"`
-	RegistryRunning  bool                   `json:"registry_running"`
-	Clusters         map[string]ClusterInfo `json:"clusters"`
+	SchemaVersion     int                    `json:"schema_version"`
+	Initialized       bool                   `json:"initialized"`
+	InitializedAt     *time.Time             `json:"initialized_at,omitempty"`
+	InstanceType      string                 `json:"instance_type,omitempty"`
+	StorageType       string                 `json:"storage_type,omitempty"`   // "nvme", "ebs-only"
+	StorageDevice     string                 `json:"storage_device,omitempty"` // "/dev/nvme1n1"
+	PackagesInstalled bool                   `json:"packages_installed"`
+	BaseClusterReady  bool                   `json:"base_cluster_ready"`
+	RegistryRunning   bool                   `json:"registry_running"`
+	Clusters          map[string]ClusterInfo `json:"clusters"`
 }
 
+// Cluster statuses. See lifecycle.go for the allowed transitions.
+const (
+	StatusPending   = "pending"   // accepted, waiting for its create to start
+	StatusCreating  = "creating"  // kind create is running
+	StatusReady     = "ready"     // created and running
+	StatusStopping  = "stopping"  // node containers are being stopped
+	StatusStopped   = "stopped"   // node containers are stopped
+	StatusPaused    = "paused"    // node containers are frozen after the cluster was idle
+	StatusDeleting  = "deleting"  // kind delete is running
+	StatusDeleted   = "deleted"   // terminal; the record is removed from state
+	StatusFailed    = "failed"    // an operation failed or was interrupted; see status_reason
+	StatusDegraded  = "degraded"  // exists but not fully working; see status_reason
+	StatusLost      = "lost"      // recorded in state but no longer known to kind
+	StatusUnmanaged = "unmanaged" // found in kind but not created through the API
+)
+
 // ClusterInfo represents information about a kind cluster
 type ClusterInfo struct {
-	Status    string     `json:"status"`    // "running", "stopped", "error"
-	Created   *time.Time `json:"created,omitempty"`
-	Type      string     `json:"type"`      // "infrastructure", "development"
-	KubeVirt  bool       `json:"kubevirt"`  // whether cluster has KubeVirt enabled
+	Status            string        `json:"status"`                  // one of the Status constants
+	StatusReason      string        `json:"status_reason,omitempty"` // why the cluster is in its status
+	LastTransition    *time.Time    `json:"last_transition,omitempty"`
+	Created           *time.Time    `json:"created,omitempty"`
+	Type              string        `json:"type"`                         // "infrastructure", "development"
+	KubeVirt          bool          `json:"kubevirt"`                     // whether cluster has KubeVirt enabled
+	Adopted           *time.Time    `json:"adopted,omitempty"`            // when an existing cluster was taken over
+	KubernetesVersion string        `json:"kubernetes_version,omitempty"` // e.g. "v1.32.0"
+	NodeImage         string        `json:"node_image,omitempty"`
+	Nodes             []string      `json:"nodes,omitempty"`        // node container names
+	Registry          bool          `json:"registry,omitempty"`     // nodes pull from the shared registry
+	FailureLogs       string        `json:"failure_logs,omitempty"` // node logs exported when a create failed
+	Topology          *Topology     `json:"topology,omitempty"`
+	Ports             []PortMapping `json:"ports,omitempty"`          // host ports allocated to the cluster
+	DataDir           string        `json:"data_dir,omitempty"`       // host directory holding the cluster's mounts
+	DataSubvolume     bool          `json:"data_subvolume,omitempty"` // DataDir is a btrfs subvolume
+	DataPolicy        string        `json:"data_policy,omitempty"`    // what happens to DataDir on delete
+	Mounts            []Mount       `json:"mounts,omitempty"`
+	Addons            []AddonStatus `json:"addons,omitempty"`        // in install order
+	ExpiresAt         *time.Time    `json:"expires_at,omitempty"`    // when the reaper deletes the cluster
+	ExpiryWarned      bool          `json:"expiry_warned,omitempty"` // the expiry warning was sent
+	LastActivity      *time.Time    `json:"last_activity,omitempty"` // last use seen by the idle monitor
+
+	Owner       string            `json:"owner,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`      // selectable with ?selector=
+	Annotations map[string]string `json:"annotations,omitempty"` // free-form notes, e.g. a ticket URL
 }
 
 // StorageConfig represents storage configuration for the host
@@ -32,17 +71,212 @@ type StorageConfig struct {
 
 // ClusterCreateRequest represents a request to create a new cluster
 type ClusterCreateRequest struct {
-	Name     string `json:"name"`
-	KubeVirt bool   `json:"kubevirt,omitempty"`
+	Name          string `json:"name"`
+	KubeVirt      bool   `json:"kubevirt,omitempty"`
+	KeepOnFailure bool   `json:"keep_on_failure,omitempty"` // keep a failed cluster for debugging instead of rolling back
+
+	// KubernetesVersion selects a supported release, e.g. "v1.31.4" or "1.31";
+	// NodeImage names one of the curated node images explicitly, or for
+	// admins any image of a supported release. The server default applies if
+	// both are empty.
+	KubernetesVersion string `json:"kubernetes_version,omitempty"`
+	NodeImage         string `json:"node_image,omitempty"`
+
+	// Topology describes the cluster's nodes; one control-plane node if nil
+	Topology *Topology `json:"topology,omitempty"`
+
+	// NodePorts are NodePort service ports to expose on host ports
+	// allocated from the NodePort range
+	NodePorts []int `json:"node_ports,omitempty"`
+
+	// Mounts are extra host directories mounted into every node, in
+	// addition to /local; DataPolicy is "delete" (default) or "retain"
+	Mounts     []Mount `json:"mounts,omitempty"`
+	DataPolicy string  `json:"data_policy,omitempty"`
+
+	// Addons are installed after the cluster is created; their
+	// dependencies are added automatically
+	Addons []AddonRequest `json:"addons,omitempty"`
+
+	// TTL (a duration such as "8h") or ExpiresAt sets when the cluster is
+	// deleted automatically; it never expires if both are empty
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Owner defaults to the authenticated caller; Labels can be used to
+	// filter the cluster list and Annotations hold free-form notes
+	Owner       string            `json:"owner,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ClusterUpdateRequest changes properties of an existing cluster. Fields
+// left empty are not changed.
+type ClusterUpdateRequest struct {
+	// TTL sets the expiry relative to now, ExpiresAt sets it absolutely and
+	// NoExpiry removes it
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	NoExpiry  bool       `json:"no_expiry,omitempty"`
+
+	// Owner replaces the owner ("" clears it). Labels and Annotations are
+	// merged into the existing ones; a key with a null value is removed.
+	Owner       *string            `json:"owner,omitempty"`
+	Labels      map[string]*string `json:"labels,omitempty"`
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
+
+// AddonRequest names an addon to install and its parameters
+type AddonRequest struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Addon statuses
+const (
+	AddonPending    = "pending"
+	AddonInstalling = "installing"
+	AddonReady      = "ready"
+	AddonFailed     = "failed"
+)
+
+// AddonStatus is the installation state of one addon of a cluster
+type AddonStatus struct {
+	Name    string            `json:"name"`
+	Version string            `json:"version,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+	Status  string            `json:"status"` // one of the Addon constants
+	Message string            `json:"message,omitempty"`
+}
+
+// Data policies applied to a cluster's data directory on delete
+const (
+	DataDelete = "delete" // remove the directory with the cluster
+	DataRetain = "retain" // move it aside to the retained data directory
+)
+
+// Mount is a named directory in the cluster's data directory mounted into
+// every node at ContainerPath
+type Mount struct {
+	Name          string `json:"name"`
+	ContainerPath string `json:"container_path"`
+	HostPath      string `json:"host_path,omitempty"` // set by the server
+}
+
+// Port mapping purposes
+const (
+	PortAPIServer = "api-server" // Kubernetes API server, bound to localhost
+	PortSSH       = "ssh"        // SSH into the cluster's workloads
+	PortNodePort  = "nodeport"   // a NodePort service port
+	PortIngress   = "ingress"    // HTTP(S) port of an ingress controller
+)
+
+// PortMapping is a host port allocated to a cluster and the node port it
+// forwards to
+type PortMapping struct {
+	Purpose       string `json:"purpose"` // one of the Port constants
+	HostPort      int    `json:"host_port"`
+	ContainerPort int    `json:"container_port"`
+}
+
+// Node roles
+const (
+	RoleControlPlane = "control-plane"
+	RoleWorker       = "worker"
+)
+
+// Topology describes the nodes of a cluster. Nodes customizes individual
+// nodes; in responses it lists every node.
+type Topology struct {
+	ControlPlanes int        `json:"control_planes"`
+	Workers       int        `json:"workers"`
+	Nodes         []NodeSpec `json:"nodes,omitempty"`
+}
+
+// NodeSpec holds the labels and taints of one node, identified by its role
+// and its position among the nodes of that role (from 0)
+type NodeSpec struct {
+	Role   string            `json:"role"`
+	Index  int               `json:"index"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Taints []Taint           `json:"taints,omitempty"`
+}
+
+// Taint is a Kubernetes node taint
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"` // "NoSchedule", "PreferNoSchedule" or "NoExecute"
 }
 
 // ClusterResponse represents a cluster in API responses
 type ClusterResponse struct {
-	Name     string     `json:"name"`
-	Status   string     `json:"status"`
-	Created  *time.Time `json:"created,omitempty"`
-	Type     string     `json:"type"`
-	KubeVirt bool       `json:"kubevirt"`
+	Name              string            `json:"name"`
+	Status            string            `json:"status"`
+	StatusReason      string            `json:"status_reason,omitempty"`
+	LastTransition    *time.Time        `json:"last_transition,omitempty"`
+	Created           *time.Time        `json:"created,omitempty"`
+	Type              string            `json:"type"`
+	KubeVirt          bool              `json:"kubevirt"`
+	Adopted           *time.Time        `json:"adopted,omitempty"`
+	KubernetesVersion string            `json:"kubernetes_version,omitempty"`
+	NodeImage         string            `json:"node_image,omitempty"`
+	Nodes             []string          `json:"nodes,omitempty"`
+	Registry          bool              `json:"registry,omitempty"`
+	FailureLogs       string            `json:"failure_logs,omitempty"`
+	Topology          *Topology         `json:"topology,omitempty"`
+	Ports             []PortMapping     `json:"ports,omitempty"`
+	DataDir           string            `json:"data_dir,omitempty"`
+	DataPolicy        string            `json:"data_policy,omitempty"`
+	Mounts            []Mount           `json:"mounts,omitempty"`
+	Addons            []AddonStatus     `json:"addons,omitempty"`
+	ExpiresAt         *time.Time        `json:"expires_at,omitempty"`
+	LastActivity      *time.Time        `json:"last_activity,omitempty"`
+	Owner             string            `json:"owner,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+	Health            *ClusterHealth    `json:"health,omitempty"` // last background probe
+}
+
+// Health statuses
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// Health check names
+const (
+	CheckNodes     = "nodes"      // node containers are running
+	CheckAPIServer = "apiserver"  // kube-apiserver /readyz succeeds
+	CheckNodeReady = "node-ready" // every node reports Ready
+)
+
+// ClusterHealth is the result of probing a cluster
+type ClusterHealth struct {
+	Status    string        `json:"status"` // healthy if every check passed
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []HealthCheck `json:"checks"`
+}
+
+// HealthCheck is the result of a single health check
+type HealthCheck struct {
+	Name      string    `json:"name"` // one of the Check constants
+	OK        bool      `json:"ok"`
+	Message   string    `json:"message,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+	Duration  string    `json:"duration"`
+}
+
+// KubernetesVersion is a Kubernetes release clusters can be created with
+type KubernetesVersion struct {
+	Version string `json:"version"`
+	Image   string `json:"image"` // kind node image, pinned by digest
+}
+
+// KubernetesVersionsResponse lists the supported Kubernetes versions
+type KubernetesVersionsResponse struct {
+	Default  string              `json:"default"`
+	Versions []KubernetesVersion `json:"versions"`
 }
 
 // RegistryStatus represents the status of the container registry
@@ -57,4 +291,86 @@ type HealthResponse struct {
 	Status      string `json:"status"`
 	Initialized bool   `json:"initialized"`
 	Version     string `json:"version"`
-}
\ No newline at end of file
+}
+
+// Reconcile findings
+const (
+	FindingLost         = "lost"          // state cluster missing from kind; marked lost
+	FindingUnmanaged    = "unmanaged"     // kind cluster missing from state; recorded as unmanaged
+	FindingRecovered    = "recovered"     // lost or degraded cluster is healthy again; marked ready
+	FindingNodesStopped = "nodes-stopped" // ready cluster has stopped node containers; marked degraded
+)
+
+// ReconcileFinding is a single difference between state and kind
+type ReconcileFinding struct {
+	Cluster string `json:"cluster"`
+	Finding string `json:"finding"`
+	Status  string `json:"status,omitempty"` // status recorded in state afterwards
+	Message string `json:"message,omitempty"`
+}
+
+// ReconcileReport is the outcome of one reconcile pass
+type ReconcileReport struct {
+	Time     time.Time          `json:"time"`
+	Duration string             `json:"duration"`
+	Clusters []string           `json:"clusters"` // clusters reported by kind
+	Findings []ReconcileFinding `json:"findings"`
+	Warnings []string           `json:"warnings,omitempty"`
+	Error    string             `json:"error,omitempty"` // set when the pass could not run
+}
+
+// Operation types
+const (
+	OperationCreateCluster  = "create-cluster"
+	OperationDeleteCluster  = "delete-cluster"
+	OperationLoadImage      = "load-image"
+	OperationStopCluster    = "stop-cluster"
+	OperationStartCluster   = "start-cluster"
+	OperationRestartCluster = "restart-cluster"
+)
+
+// Operation phases
+const (
+	OperationPending   = "pending"
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+)
+
+// Cluster event types
+const (
+	EventStarted   = "started"
+	EventOutput    = "output"
+	EventCompleted = "completed"
+	EventExpiring  = "expiring" // the cluster will be deleted at its expiry
+	EventExpired   = "expired"  // the cluster expired and is being deleted
+)
+
+// ClusterEvent is a single entry in a cluster's event stream
+type ClusterEvent struct {
+	Type      string    `json:"type"` // one of the Event constants
+	Cluster   string    `json:"cluster"`
+	Action    string    `json:"action,omitempty"`    // "create-cluster", "delete-cluster", "load-image"
+	Operation string    `json:"operation,omitempty"` // operation ID, if the action is tracked
+	Message   string    `json:"message,omitempty"`
+	Phase     string    `json:"phase,omitempty"` // final phase for completed events
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Operation represents a long-running asynchronous operation on a cluster
+type Operation struct {
+	ID        string     `json:"id"`
+	Type      string     `json:"type"` // "create-cluster", "delete-cluster"
+	Cluster   string     `json:"cluster"`
+	Phase     string     `json:"phase"` // "pending", "running", "succeeded", "failed"
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Output    string     `json:"output,omitempty"` // captured kind/podman output
+}
+
+// Done reports whether the operation has finished, successfully or not
+func (o *Operation) Done() bool {
+	return o.Phase == OperationSucceeded || o.Phase == OperationFailed
+}
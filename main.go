@@ -1,26 +1,67 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/kylape/host-manager/internal/auth"
 	"github.com/kylape/host-manager/internal/host"
 	"github.com/kylape/host-manager/internal/logger"
+	"github.com/kylape/host-manager/internal/pki"
 	"github.com/kylape/host-manager/internal/server"
 	"github.com/kylape/host-manager/internal/state"
 )
 
 func main() {
 	// Parse command line flags
+	var listenAddrs listFlag
+	flag.Var(&listenAddrs, "listen", "Address to serve on: tcp://HOST:PORT, HOST:PORT or unix:///PATH (repeatable; default :PORT, or 127.0.0.1:PORT without authentication)")
 	var (
 		help          = flag.Bool("help", false, "Show help message")
 		port          = flag.String("port", "8080", "HTTP server port")
 		foreground    = flag.Bool("foreground", false, "Run in foreground instead of background")
 		auditLog      = flag.Bool("audit", false, "Enable HTTP request audit logging")
 		skipBootstrap = flag.Bool("skip-bootstrap", false, "Skip host initialization and run server only")
+		authEnabled   = flag.Bool("auth", false, "Require bearer-token authentication for API requests")
+		tokenFile     = flag.String("token-file", auth.TokenFilePath, "Path to the API token file")
+		corsOrigins   = flag.String("cors-origins", "", "Comma-separated browser origins allowed to call the API")
+		addToken      = flag.String("add-token", "", "Create an API token with the given name, print it and exit")
+		tokenRole     = flag.String("token-role", auth.RoleViewer, "Role for --add-token: viewer, developer or admin")
+		revokeToken   = flag.String("revoke-token", "", "Revoke the API token with the given name and exit")
+		tlsCert       = flag.String("tls-cert", "", "TLS certificate file; enables HTTPS")
+		tlsKey        = flag.String("tls-key", "", "TLS private key file")
+		tlsClientCA   = flag.String("tls-client-ca", "", "CA file for verifying client certificates; enables mTLS")
+		tlsBootstrap  = flag.Bool("tls-bootstrap", false, "Serve HTTPS with a self-signed host CA generated on first run")
+		tlsHosts      = flag.String("tls-hosts", "", "Extra comma-separated DNS names/IPs for the bootstrap server certificate")
+		mtls          = flag.Bool("mtls", false, "Require client certificates signed by the bootstrap CA")
+		issueCert     = flag.String("issue-client-cert", "", "Issue a client certificate with the given name from the bootstrap CA and exit")
+		socketPeers   = flag.String("socket-peers", auth.DefaultPeerPolicy, "Unix socket access rules, e.g. uid:0=admin,group:wheel=developer")
+		drainTimeout  = flag.Duration("shutdown-timeout", 10*time.Minute, "How long to wait for running cluster operations on SIGTERM/SIGINT")
+		stateBackend  = flag.String("state-backend", state.BackendJSON, "State storage backend: json or bolt")
+		stateDB       = flag.String("state-db", state.DatabasePath, "Database file for the bolt state backend")
+		importState   = flag.String("import-state", "", "Import the given JSON state file into the bolt database and exit")
+		reconcileIntv = flag.Duration("reconcile-interval", time.Minute, "How often to compare state with kind clusters (0 disables)")
+		healthIntv    = flag.Duration("health-interval", 30*time.Second, "How often to probe cluster health (0 disables)")
+		reapIntv      = flag.Duration("reap-interval", time.Minute, "How often to delete expired clusters (0 disables)")
+		expiryWarning = flag.Duration("expiry-warning", time.Hour, "How long before a cluster expires to warn about it")
+		idleTimeout   = flag.Duration("idle-timeout", 0, "Pause clusters unused for this long (0 disables)")
+		idleInterval  = flag.Duration("idle-check-interval", time.Minute, "How often to check clusters for use")
+		idleRequests  = flag.Bool("idle-api-requests", false, "Count requests to a cluster's API server as use (addons polling it keep clusters awake)")
+		apiPorts      = flag.String("api-port-range", state.DefaultPortRanges.APIServer.String(), "Host ports for cluster API servers")
+		sshPorts      = flag.String("ssh-port-range", state.DefaultPortRanges.SSH.String(), "Host ports for cluster SSH mappings")
+		nodePortPorts = flag.String("nodeport-host-range", state.DefaultPortRanges.NodePort.String(), "Host ports for NodePort service mappings")
 	)
 	flag.Parse()
 
@@ -44,6 +85,36 @@ func main() {
 		return
 	}
 
+	// Client certificate issuance runs and exits without starting the service
+	if *issueCert != "" {
+		certPath, keyPath := *issueCert+".crt", *issueCert+".key"
+		if err := pki.IssueClientCert(pkiDir(), *issueCert, certPath, keyPath); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Client certificate written to %s and %s\n", certPath, keyPath)
+		fmt.Printf("CA certificate: %s\n", pki.BundleIn(pkiDir()).CACert)
+		return
+	}
+
+	// Token management commands run and exit without starting the service
+	if *addToken != "" || *revokeToken != "" {
+		if err := manageTokens(*tokenFile, *addToken, *tokenRole, *revokeToken); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// State import runs and exits without starting the service
+	if *importState != "" {
+		if err := importStateFile(*importState, *stateDB); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize logging
 	logger := logger.New(*foreground)
 
@@ -57,10 +128,35 @@ func main() {
 		return
 	}
 
-	logger.Info("Starting host manager", "port", *port, "audit", *auditLog, "skip_bootstrap", *skipBootstrap)
+	logger.Info("Starting host manager", "port", *port, "audit", *auditLog, "auth", *authEnabled, "skip_bootstrap", *skipBootstrap, "state_backend", *stateBackend)
 
 	// Initialize state manager
-	stateManager := state.NewManager()
+	statePath := state.StateFilePath
+	if *stateBackend == state.BackendBolt {
+		statePath = *stateDB
+	}
+	store, err := state.OpenStore(*stateBackend, statePath)
+	if err != nil {
+		logger.Error("Failed to open state store", "error", err)
+		os.Exit(1)
+	}
+	stateManager := state.NewManagerWithStore(store)
+	defer stateManager.Close()
+
+	portRanges, err := parsePortRanges(*apiPorts, *sshPorts, *nodePortPorts)
+	if err != nil {
+		logger.Error("Invalid port range", "error", err)
+		os.Exit(1)
+	}
+	stateManager.SetPortRanges(portRanges)
+
+	// Upgrade the state file written by an older version before using it
+	if from, err := stateManager.Migrate(); err != nil {
+		logger.Error("Failed to migrate state file", "error", err)
+		os.Exit(1)
+	} else if from != state.CurrentSchemaVersion {
+		logger.Info("Migrated state file", "from_schema", from, "to_schema", state.CurrentSchemaVersion)
+	}
 
 	// Only run bootstrap if not skipped
 	if !*skipBootstrap {
@@ -91,13 +187,249 @@ func main() {
 
 	// Start HTTP server for runtime operations
 	srv := server.New(stateManager, logger, *auditLog)
-	logger.Info("HTTP server ready", "address", ":"+*port)
-	if err := srv.Start(":" + *port); err != nil {
+
+	// Clusters left mid-create or mid-delete by a previous run need attention
+	if interrupted, err := srv.RecoverInterrupted(); err != nil {
+		logger.Warn("Failed to check for interrupted clusters", "error", err)
+	} else if len(interrupted) > 0 {
+		logger.Warn("Clusters were interrupted by a previous shutdown; delete and recreate them", "clusters", strings.Join(interrupted, ","))
+	}
+	if *authEnabled {
+		tokens, err := auth.NewTokenStore(*tokenFile)
+		if err != nil {
+			logger.Error("Failed to load API tokens", "error", err)
+			os.Exit(1)
+		}
+		srv.EnableAuth(tokens)
+	}
+
+	tlsConfig, err := buildTLSConfig(*tlsCert, *tlsKey, *tlsClientCA, *tlsBootstrap, *mtls, *tlsHosts)
+	if err != nil {
+		logger.Error("Failed to configure TLS", "error", err)
+		os.Exit(1)
+	}
+
+	// Without tokens or client certificates anyone who can reach a TCP
+	// listener has full access, so only loopback addresses are served
+	secured := *authEnabled || (tlsConfig != nil && tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert)
+	if len(listenAddrs) == 0 {
+		listenAddrs = listFlag{":" + *port}
+		if !secured {
+			listenAddrs = listFlag{"127.0.0.1:" + *port}
+		}
+	}
+	if !secured {
+		for _, addr := range listenAddrs {
+			if network, address := parseListenAddr(addr); network == "tcp" && !loopbackAddr(address) {
+				logger.Error("Refusing to serve the API on a non-loopback address without authentication; use --auth or --mtls", "address", address)
+				os.Exit(1)
+			}
+		}
+	}
+	srv.SetCORSOrigins(splitList(*corsOrigins))
+
+	peers, err := auth.ParsePeerPolicy(*socketPeers)
+	if err != nil {
+		logger.Error("Invalid --socket-peers", "error", err)
+		os.Exit(1)
+	}
+	srv.EnablePeerAuth(peers)
+
+	if *reconcileIntv > 0 {
+		srv.StartReconciler(*reconcileIntv)
+	}
+	if *healthIntv > 0 {
+		srv.StartHealthProber(*healthIntv)
+	}
+	if *reapIntv > 0 {
+		srv.StartReaper(*reapIntv, *expiryWarning)
+	}
+	if *idleTimeout > 0 {
+		srv.StartIdleMonitor(server.IdleOptions{
+			Interval:    *idleInterval,
+			Timeout:     *idleTimeout,
+			APIRequests: *idleRequests,
+		})
+	}
+
+	if err := serve(srv, listenAddrs, tlsConfig, *drainTimeout, logger); err != nil {
 		logger.Error("Server failed", "error", err)
+		stateManager.Close()
 		os.Exit(1)
 	}
 }
 
+// importStateFile copies the JSON state file at path into the bolt database
+func importStateFile(path, dbPath string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cannot read %s: %w", path, err)
+	}
+
+	store, err := state.OpenBoltStore(dbPath)
+	if err != nil {
+		return err
+	}
+	manager := state.NewManagerWithStore(store)
+	defer manager.Close()
+
+	imported, err := manager.Import(state.NewFileStore(path))
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", path, err)
+	}
+
+	fmt.Printf("Imported %s into %s (%d clusters)\n", path, dbPath, len(imported.Clusters))
+	fmt.Printf("Start the service with --state-backend %s --state-db %s to use it.\n", state.BackendBolt, dbPath)
+	return nil
+}
+
+// parsePortRanges parses the host port range flags
+func parsePortRanges(apiServer, ssh, nodePort string) (state.PortRanges, error) {
+	var ranges state.PortRanges
+	var err error
+	if ranges.APIServer, err = state.ParsePortRange(apiServer); err != nil {
+		return ranges, err
+	}
+	if ranges.SSH, err = state.ParsePortRange(ssh); err != nil {
+		return ranges, err
+	}
+	if ranges.NodePort, err = state.ParsePortRange(nodePort); err != nil {
+		return ranges, err
+	}
+	return ranges, nil
+}
+
+// listFlag is a flag that may be given multiple times
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// serve runs the server on every listen address until one of them fails or
+// SIGTERM/SIGINT is received, in which case it shuts down gracefully
+func serve(srv *server.Server, addrs []string, tlsConfig *tls.Config, drainTimeout time.Duration, logger *logger.Logger) error {
+	errCh := make(chan error, len(addrs))
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigCh)
+
+	for _, addr := range addrs {
+		network, address := parseListenAddr(addr)
+		switch {
+		case network == "unix":
+			logger.Info("HTTP server ready", "socket", address)
+			go func() { errCh <- srv.StartUnix(address) }()
+		case tlsConfig != nil:
+			logger.Info("HTTPS server ready", "address", address)
+			go func() { errCh <- srv.StartTLS(address, tlsConfig) }()
+		default:
+			logger.Info("HTTP server ready", "address", address)
+			go func() { errCh <- srv.Start(address) }()
+		}
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case sig := <-sigCh:
+		logger.Info("Shutting down", "signal", sig.String(), "timeout", drainTimeout.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	logger.Info("Shutdown complete")
+	return nil
+}
+
+// parseListenAddr splits a --listen value into network and address
+func parseListenAddr(addr string) (string, string) {
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		return "unix", path
+	}
+	return "tcp", strings.TrimPrefix(addr, "tcp://")
+}
+
+// loopbackAddr reports whether a TCP listen address only accepts
+// connections from this host
+func loopbackAddr(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// pkiDir is where the bootstrap CA and certificates live, next to the state file
+func pkiDir() string {
+	return filepath.Dir(state.StateFilePath)
+}
+
+// buildTLSConfig returns the server TLS configuration, or nil to serve plain HTTP
+func buildTLSConfig(certFile, keyFile, clientCA string, bootstrap, mtls bool, extraHosts string) (*tls.Config, error) {
+	if bootstrap {
+		hosts := []string{"localhost", "127.0.0.1", "::1", "host.docker.internal", "host.containers.internal"}
+		if hostname, err := os.Hostname(); err == nil {
+			hosts = append(hosts, hostname)
+		}
+		for _, host := range strings.Split(extraHosts, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+
+		bundle, err := pki.Bootstrap(pkiDir(), hosts)
+		if err != nil {
+			return nil, err
+		}
+		if certFile == "" {
+			certFile, keyFile = bundle.ServerCert, bundle.ServerKey
+		}
+		if mtls && clientCA == "" {
+			clientCA = bundle.CACert
+		}
+	}
+
+	if mtls && clientCA == "" {
+		return nil, fmt.Errorf("--mtls requires --tls-client-ca or --tls-bootstrap")
+	}
+
+	if certFile == "" && keyFile == "" {
+		if clientCA != "" {
+			return nil, fmt.Errorf("--tls-client-ca requires a server certificate")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+
+	return pki.ServerTLSConfig(certFile, keyFile, clientCA)
+}
+
 func showHelp() {
 	fmt.Printf(`Host Manager - Unified host management service for EC2-based development environments
 
@@ -106,9 +438,54 @@ Usage: %s [options]
 Options:
   --help             Show this help message
   --port PORT        HTTP server port (default: 8080)
+  --listen ADDR      Serve on ADDR instead of :PORT; repeat to serve on several.
+                     ADDR is tcp://HOST:PORT, HOST:PORT or unix:///PATH. Without
+                     --auth or --mtls only loopback TCP addresses are allowed
+                     and the default is 127.0.0.1:PORT
+  --socket-peers RULES  Unix socket access by peer UID/GID, e.g.
+                     uid:0=admin,gid:1000=developer,group:wheel=admin (default: uid:0=admin)
+  --shutdown-timeout DURATION  Time to let running cluster operations finish on
+                     SIGTERM/SIGINT before marking them interrupted (default: 10m)
   --foreground       Run in foreground instead of background
   --audit            Enable HTTP request audit logging
   --skip-bootstrap   Skip host initialization and run server only (for containers)
+  --auth             Require bearer-token authentication for API requests
+  --token-file PATH  API token file (default: /etc/host-manager-tokens.json)
+  --cors-origins LIST  Comma-separated browser origins allowed to call the API
+                     (default: none; cross-origin mutating requests are refused)
+  --add-token NAME   Create an API token, print it and exit (see --token-role)
+  --token-role ROLE  Role for --add-token: viewer, developer or admin (default: viewer)
+  --revoke-token NAME  Revoke an API token and exit
+  --tls-cert FILE    Serve HTTPS with this certificate (requires --tls-key)
+  --tls-key FILE     Private key for --tls-cert
+  --tls-client-ca FILE  Require client certificates signed by this CA (mTLS)
+  --tls-bootstrap    Serve HTTPS with a self-signed host CA created on first run
+                     (stored in /etc next to the state file)
+  --tls-hosts LIST   Extra comma-separated names/IPs for the bootstrap certificate
+  --mtls             Require client certificates signed by the bootstrap CA
+  --issue-client-cert NAME  Write NAME.crt/NAME.key signed by the bootstrap CA and exit
+  --state-backend NAME  Where host state is kept: json or bolt (default: json)
+  --state-db PATH    Database file for the bolt backend (default: /etc/host-manager-state.db)
+  --import-state FILE  Import a JSON state file into the bolt database and exit
+  --reconcile-interval DURATION  How often to compare state with kind and podman,
+                     marking missing clusters lost and unknown ones unmanaged
+                     (default: 1m, 0 disables)
+  --health-interval DURATION  How often to probe node containers, the API server
+                     and node readiness of each cluster (default: 30s, 0 disables)
+  --reap-interval DURATION  How often to delete clusters past their expiry
+                     (default: 1m, 0 disables)
+  --expiry-warning DURATION  How long before expiry to warn via events and the
+                     audit log (default: 1h)
+  --idle-timeout DURATION  Pause the node containers of development clusters
+                     unused for this long (default: 0, disabled)
+  --idle-check-interval DURATION  How often to look for idle clusters (default: 1m)
+  --idle-api-requests  Also count requests to each cluster's API server as use;
+                     addons that poll the API server, such as KubeVirt or
+                     cert-manager, then keep their cluster from pausing
+  --api-port-range FIRST-LAST  Host ports allocated to cluster API servers (default: 6443-6542)
+  --ssh-port-range FIRST-LAST  Host ports allocated to cluster SSH mappings (default: 2222-2321)
+  --nodeport-host-range FIRST-LAST  Host ports allocated to NodePort mappings
+                     requested with "node_ports" (default: 40000-40999)
 
 Features:
   - Auto-initialization: Complete host setup on first run
@@ -117,11 +494,28 @@ Features:
   - State persistence: Tracks what's been initialized
 
 API Endpoints:
-  GET  /health                      Service health check
-  GET  /clusters                    List all clusters
-  POST /clusters                    Create new cluster
-  GET  /clusters/{name}/kubeconfig  Get kubeconfig for cluster
-  DELETE /clusters/{name}           Delete cluster
+  GET    /health                      Service health check
+  GET    /host/status                 Host initialization status
+  GET    /host/reconcile              Last state/kind reconcile report (POST runs one now)
+  GET    /version                     Service version
+  GET    /metrics                     Prometheus metrics
+  GET    /kubernetes-versions         Kubernetes versions clusters can be created with
+  GET    /clusters                    List clusters (?selector= filters by label)
+  POST   /clusters                    Create new cluster (returns an operation)
+  GET    /clusters/{name}             Get a cluster, including its allocated host ports
+  PATCH  /clusters/{name}             Change a cluster's expiry, owner, labels or annotations
+  DELETE /clusters/{name}             Delete cluster (returns an operation)
+  POST   /clusters/{name}/adopt       Manage an existing kind cluster
+  POST   /clusters/{name}/stop        Stop a cluster's node containers (returns an operation)
+  POST   /clusters/{name}/start       Start a stopped cluster or resume a paused one (returns an operation)
+  POST   /clusters/{name}/restart     Stop and start a cluster (returns an operation)
+  GET    /clusters/{name}/kubeconfig  Get kubeconfig for cluster
+  POST   /clusters/{name}/load-image  Load an image into a cluster
+  GET    /clusters/{name}/events      Stream cluster events (Server-Sent Events)
+  GET    /operations                  List cluster operations
+  GET    /operations/{id}             Get operation status and output
+  GET    /registry/status             Shared image registry status
+  POST   /registry/start              Start the shared image registry
 
 Example Usage:
   # Start service (auto-initializes on fresh host)
@@ -130,14 +524,55 @@ Example Usage:
   # Start on custom port
   %s --port 9090
 
+  # Serve on TCP and a local Unix socket for devcontainers
+  %s --auth --listen :8080 --listen unix:///run/host-manager.sock --socket-peers uid:0=admin,group:wheel=developer
+
   # Run in container/devcontainer (skip bootstrap, kind cluster management only)
   %s --skip-bootstrap --foreground
 
   # Create development cluster
   curl -X POST http://localhost:8080/clusters -d '{"name": "my-dev-cluster"}'
 
+  # Create a developer token and require authentication
+  %s --add-token alice --token-role developer
+  %s --auth
+
+  # Serve HTTPS with a generated host CA and require client certificates
+  %s --tls-bootstrap --mtls
+  %s --issue-client-cert alice
+
+  # Move host state from the JSON file into the embedded database
+  %s --import-state /etc/host-manager-state.json
+  %s --state-backend bolt
+
 For more information, see README.md
-`, os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+}
+
+// manageTokens adds or revokes an API token in the token file
+func manageTokens(path, addName, role, revokeName string) error {
+	tokens, err := auth.NewTokenStore(path)
+	if err != nil {
+		return err
+	}
+
+	if revokeName != "" {
+		if err := tokens.Remove(revokeName); err != nil {
+			return err
+		}
+		fmt.Printf("Token %s revoked\n", revokeName)
+	}
+
+	if addName != "" {
+		token, err := tokens.Add(addName, role)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Token for %s (role %s):\n%s\n", addName, role, token)
+		fmt.Println("Store it now - only its hash is kept on the host.")
+	}
+
+	return nil
 }
 
 // daemonize implements proper POSIX daemonization
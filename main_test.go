@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"127.0.0.1:8080", true},
+		{"127.0.0.2:8080", true},
+		{"[::1]:8080", true},
+		{"localhost:8080", true},
+		{":8080", false},
+		{"0.0.0.0:8080", false},
+		{"[::]:8080", false},
+		{"10.0.0.5:8080", false},
+		{"example.com:8080", false},
+		{"127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := loopbackAddr(tt.address); got != tt.want {
+			t.Errorf("loopbackAddr(%q) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+}